@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// configErr is the error of loading the config file, reported when the command runs
+var configErr error
+
+// defaultConfigPath returns the path of the config file used without --config
+// ($XDG_CONFIG_HOME/sma_chg_log/config.yaml, defaulting to ~/.config)
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sma_chg_log", "config.yaml"), nil
+}
+
+// loadConfigFile reads the config file and merges the selected profile over its top-level settings,
+// flags and environment variables take precedence over both
+func loadConfigFile() error {
+	path := viper.GetString("config")
+	profile := viper.GetString("profile")
+
+	if path == "" {
+		defaultPath, err := defaultConfigPath()
+		if err == nil {
+			_, err = os.Stat(defaultPath)
+		}
+		if err != nil {
+			if profile != "" {
+				return fmt.Errorf("profile %q selected, but there is no config file (use --config)", profile)
+			}
+			return nil
+		}
+		path = defaultPath
+	}
+
+	// the file is read separately, so its settings can be told apart from flags and defaults
+	file := viper.New()
+	file.SetConfigFile(path)
+	if err := file.ReadInConfig(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("config file %s not found", path)
+		}
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	settings := file.AllSettings()
+	profiles, _ := settings["profiles"].(map[string]any)
+	delete(settings, "profiles")
+	if err := validateSettings(settings, path); err != nil {
+		return err
+	}
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return err
+	}
+
+	// the profile may be selected by the config file itself
+	profile = viper.GetString("profile")
+	if profile == "" {
+		return nil
+	}
+
+	// keys are case-insensitive in viper
+	selected, ok := profiles[strings.ToLower(profile)]
+	if !ok {
+		return fmt.Errorf("profile %q not found in %s (available: %s)", profile, path,
+			strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+	profileSettings, ok := selected.(map[string]any)
+	if !ok {
+		return fmt.Errorf("profile %q in %s must be a map of settings", profile, path)
+	}
+	if err := validateSettings(profileSettings, path+" profile "+profile); err != nil {
+		return err
+	}
+	return viper.MergeConfigMap(profileSettings)
+}
+
+// validateSettings rejects settings not corresponding to a flag, e.g. misspelled ones
+// (only the profile and the device list have no flag)
+func validateSettings(settings map[string]any, source string) error {
+	known := map[string]struct{}{"profile": {}, "devices": {}}
+	addFlags := func(flag *pflag.Flag) {
+		known[flag.Name] = struct{}{}
+	}
+	rootCmd.PersistentFlags().VisitAll(addFlags)
+	for _, cmd := range append([]*cobra.Command{rootCmd}, rootCmd.Commands()...) {
+		cmd.Flags().VisitAll(addFlags)
+	}
+	// settings that make no sense in a file
+	for _, name := range []string{"config", "help", "password-stdin"} {
+		delete(known, name)
+	}
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if _, ok := known[key]; !ok {
+			errs = append(errs, fmt.Errorf("unknown setting %q in %s", key, source))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unmarshalConfig reports errors of the config file and fills cfg from flags, environment and config file
+func unmarshalConfig() error {
+	if configErr != nil {
+		return configErr
+	}
+	return viper.Unmarshal(&cfg)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const testConfig = `host: device.local
+username: admin
+timeout: 10s
+profile: garage
+profiles:
+  garage:
+    host: garage.local
+  Carport:
+    host: carport.local
+    username: reporter
+`
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		config   string
+		profile  string
+		host     string
+		username string
+		wantErr  string
+	}{
+		{name: "profile selected by the file", config: path, host: "garage.local", username: "admin"},
+		// profile names are case-insensitive
+		{name: "profile selected by flag", config: path, profile: "carport", host: "carport.local", username: "reporter"},
+		{name: "without profiles", config: writeConfig("plain.yaml", "host: device.local\n"), host: "device.local"},
+		{name: "unknown profile", config: path, profile: "office", wantErr: `profile "office" not found in ` + path + " (available: carport, garage)"},
+		{name: "unknown setting", config: writeConfig("typo.yaml", "hostname: device.local\n"), wantErr: `unknown setting "hostname"`},
+		{name: "unknown setting in profile", config: writeConfig("profile-typo.yaml", "profiles:\n  garage:\n    usr: admin\n"), profile: "garage",
+			wantErr: `unknown setting "usr" in ` + filepath.Join(dir, "profile-typo.yaml") + " profile garage"},
+		{name: "profile without settings", config: writeConfig("empty-profile.yaml", "profiles:\n  garage: garage.local\n"), profile: "garage", wantErr: "must be a map of settings"},
+		{name: "missing file", config: filepath.Join(dir, "missing.yaml"), wantErr: "not found"},
+		// the default file doesn't exist in XDG_CONFIG_HOME
+		{name: "profile without file", profile: "garage", wantErr: "there is no config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the tests of the package don't depend on the flag bindings lost by the reset
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("config", tt.config)
+			if tt.profile != "" {
+				viper.Set("profile", tt.profile)
+			}
+
+			err := loadConfigFile()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadConfigFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfigFile() error = %v", err)
+			}
+			if host, username := viper.GetString("host"), viper.GetString("username"); host != tt.host || username != tt.username {
+				t.Errorf("host, username = %s, %s, want %s, %s", host, username, tt.host, tt.username)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joshiste/sma_chg_log/internal/credentials"
+)
+
+// resolvePassword sets the password from the configured source: --password-stdin, --password-file,
+// --password/SMA_PASSWORD or else the password stored with the login command
+func (c *Config) resolvePassword() error {
+	if c.passwordResolved {
+		return nil
+	}
+	c.passwordResolved = true
+
+	sources := 0
+	for _, given := range []bool{c.Password != "", c.PasswordFile != "", c.PasswordStdin} {
+		if given {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of --password (or SMA_PASSWORD), --password-file and --password-stdin can be used")
+	}
+
+	switch {
+	case c.PasswordStdin:
+		if slices.Contains(c.Input, "-") {
+			return errors.New("--password-stdin cannot be combined with --input -")
+		}
+		password, err := readPassword(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		c.Password = password
+	case c.PasswordFile != "":
+		password, err := readPasswordFile(c.PasswordFile)
+		if err != nil {
+			return err
+		}
+		c.Password = password
+	case c.Password == "" && c.Host != "" && c.Username != "":
+		password, err := lookupPassword(c.Host, c.Username)
+		if err != nil {
+			return err
+		}
+		c.Password = password
+	}
+
+	return nil
+}
+
+// readPasswordFile reads the password from the first line of a file
+func readPasswordFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	password, err := readPassword(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	return password, nil
+}
+
+// lookupPassword returns the password stored with the login command, empty if there is none
+// (the missing password is reported by the caller)
+func lookupPassword(host, username string) (string, error) {
+	store, err := openCredentialStore()
+	if err != nil || store == nil {
+		return "", err
+	}
+
+	password, err := store.Get(host, username)
+	if errors.Is(err, credentials.ErrNotFound) {
+		slog.Debug("no password in keyring", "keyring", store.String(), "host", host, "username", username)
+		return "", nil
+	}
+	if err != nil {
+		slog.Warn("failed to read password from keyring", "keyring", store.String(), "error", err)
+		return "", nil
+	}
+	slog.Debug("using password from keyring", "keyring", store.String(), "host", host, "username", username)
+	return password, nil
+}
+
+// readPassword reads the first line, without the line break
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	return password, nil
+}
+
+// openCredentialStore opens the configured keyring (nil if disabled)
+func openCredentialStore() (credentials.Store, error) {
+	return credentials.Open(cfg.Keyring, cfg.KeyringFile, cfg.KeyringPassphrase)
+}
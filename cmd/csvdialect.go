@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+
+	"github.com/joshiste/sma_chg_log/internal/output"
+)
+
+// csvDialect returns the preset selected with --csv-dialect with the dialect flags and --lang applied
+func csvDialect() (output.CSVDialect, error) {
+	name := viper.GetString("csv-dialect")
+	dialect, ok := output.CSVDialects[name]
+	if !ok {
+		return output.CSVDialect{}, fmt.Errorf("unknown CSV dialect %q (available: %s)", name,
+			strings.Join(slices.Sorted(maps.Keys(output.CSVDialects)), ", "))
+	}
+
+	if value := viper.GetString("csv-delimiter"); value != "" {
+		delimiter, err := parseCSVRune("csv-delimiter", value)
+		if err != nil {
+			return output.CSVDialect{}, err
+		}
+		dialect.Delimiter = delimiter
+	}
+	if value := viper.GetString("csv-decimal"); value != "" {
+		decimal, err := parseCSVRune("csv-decimal", value)
+		if err != nil {
+			return output.CSVDialect{}, err
+		}
+		dialect.Decimal = decimal
+	}
+	if value := viper.GetString("csv-date-layout"); value != "" {
+		dialect.DateLayout = value
+	}
+	if value := viper.GetString("csv-time-layout"); value != "" {
+		dialect.TimeLayout = value
+	}
+	// the header follows --lang, unless its language is given explicitly
+	if value := viper.GetString("csv-header-lang"); value != "" {
+		dialect.Language = value
+	} else if viper.IsSet("lang") {
+		dialect.Language = viper.GetString("lang")
+	}
+	if viper.IsSet("csv-bom") {
+		dialect.BOM = viper.GetBool("csv-bom")
+	}
+	if value := viper.GetString("csv-quote"); value != "" {
+		dialect.Quote = value
+	}
+
+	return dialect, dialect.Validate()
+}
+
+// parseCSVRune parses a single character, "tab" for the tab character
+func parseCSVRune(flag, value string) (rune, error) {
+	if value == "tab" || value == `\t` {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) {
+		return 0, fmt.Errorf("%s must be a single character, not %q", flag, value)
+	}
+	return r, nil
+}
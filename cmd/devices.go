@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joshiste/sma_chg_log/internal/source"
+)
+
+// Device is one of several devices to fetch messages from; unset settings default to the global ones
+type Device struct {
+	Name           string
+	Host           string
+	Username       string
+	Password       string
+	PasswordFile   string `mapstructure:"password-file"`
+	Insecure       bool
+	CACert         string `mapstructure:"ca-cert"`
+	TLSFingerprint string `mapstructure:"tls-fingerprint"`
+}
+
+// multiDevice reports whether devices are given with --device or the devices setting instead of --host
+func (c *Config) multiDevice() bool {
+	return len(c.DeviceFlags) > 0 || len(c.Devices) > 0
+}
+
+// validateDevices checks the devices given with --device and the devices setting;
+// names default to the host, usernames and passwords to the global ones
+func (c *Config) validateDevices() []error {
+	if c.devicesResolved {
+		return nil
+	}
+	c.devicesResolved = true
+
+	var errs []error
+	if c.Host != "" {
+		errs = append(errs, errors.New("--host cannot be combined with --device or the devices setting"))
+	}
+	for _, value := range c.DeviceFlags {
+		device, err := parseDeviceFlag(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.Devices = append(c.Devices, device)
+	}
+
+	names := make(map[string]struct{}, len(c.Devices))
+	for i := range c.Devices {
+		device := &c.Devices[i]
+		if device.Host == "" {
+			errs = append(errs, fmt.Errorf("device %d: host is required", i+1))
+			continue
+		}
+		device.Host = normalizeHost(device.Host)
+		if device.Name == "" {
+			device.Name = strings.TrimPrefix(strings.TrimPrefix(device.Host, "https://"), "http://")
+		}
+		if _, ok := names[device.Name]; ok {
+			errs = append(errs, fmt.Errorf("device %s: name is not unique", device.Name))
+		}
+		names[device.Name] = struct{}{}
+
+		if device.Username == "" {
+			device.Username = c.Username
+		}
+		if device.Username == "" {
+			errs = append(errs, fmt.Errorf("device %s: username is required (use --username or the device's username setting)", device.Name))
+			continue
+		}
+
+		if err := c.resolveDevicePassword(device); err != nil {
+			errs = append(errs, fmt.Errorf("device %s: %w", device.Name, err))
+		} else if device.Password == "" {
+			errs = append(errs, fmt.Errorf("device %s: password is required (use the device's password or password-file setting, the login command or a global password)", device.Name))
+		}
+	}
+
+	return errs
+}
+
+// resolveDevicePassword sets the password of the device from its own settings,
+// the password stored with the login command or else the global password
+func (c *Config) resolveDevicePassword(device *Device) error {
+	if device.Password != "" {
+		return nil
+	}
+
+	if device.PasswordFile != "" {
+		password, err := readPasswordFile(device.PasswordFile)
+		device.Password = password
+		return err
+	}
+
+	password, err := lookupPassword(device.Host, device.Username)
+	if err != nil {
+		return err
+	}
+	if password != "" {
+		device.Password = password
+		return nil
+	}
+
+	if err := c.resolvePassword(); err != nil {
+		return err
+	}
+	device.Password = c.Password
+	return nil
+}
+
+// parseDeviceFlag parses a --device value: [name=][username@]host
+func parseDeviceFlag(value string) (Device, error) {
+	var device Device
+	name, rest, ok := strings.Cut(value, "=")
+	if ok {
+		device.Name = name
+	} else {
+		rest = name
+	}
+
+	scheme := ""
+	for _, prefix := range []string{"https://", "http://"} {
+		if strings.HasPrefix(rest, prefix) {
+			scheme, rest = prefix, strings.TrimPrefix(rest, prefix)
+		}
+	}
+	if username, host, ok := strings.Cut(rest, "@"); ok {
+		device.Username, rest = username, host
+	}
+	device.Host = scheme + rest
+
+	if device.Host == "" || (ok && device.Name == "") {
+		return Device{}, fmt.Errorf("invalid device %q (format: [name=][username@]host)", value)
+	}
+	return device, nil
+}
+
+// deviceSources creates a client per device, merged into one source
+func deviceSources() (source.Source, error) {
+	sources := make([]source.Named, 0, len(cfg.Devices))
+	for _, device := range cfg.Devices {
+		deviceClient, err := newDeviceClient(device)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: %w", device.Name, err)
+		}
+		sources = append(sources, source.Named{Name: device.Name, Source: deviceClient})
+	}
+	return source.Merge(sources...), nil
+}
@@ -2,11 +2,11 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/joshiste/sma_chg_log/internal/client"
 	"github.com/joshiste/sma_chg_log/internal/models"
 	"github.com/joshiste/sma_chg_log/internal/output"
 )
@@ -29,12 +29,16 @@ func runEvents(cmd *cobra.Command, args []string) error {
 		return errors.New("only 'json' fromat supported for events command")
 	}
 
-	apiClient := client.New(cfg.Host, cfg.Username, cfg.Password)
+	messageSource, err := newSource()
+	if err != nil {
+		return err
+	}
 	formatter := output.NewMessageFormatter(cfg.Writer)
 
-	err := apiClient.FetchAllMessages(cfg.From, cfg.Until, func(messages []models.Message) bool {
+	var writeErr error
+	err = messageSource.FetchAllMessages(cmd.Context(), cfg.From, cfg.Until, func(messages []models.Message) bool {
 		for _, msg := range filterMessages(messages) {
-			if err := formatter.WriteMessage(msg); err != nil {
+			if writeErr = formatter.WriteMessage(msg); writeErr != nil {
 				return false
 			}
 		}
@@ -43,6 +47,9 @@ func runEvents(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write message: %w", writeErr)
+	}
 
 	return formatter.Flush()
 }
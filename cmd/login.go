@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"github.com/joshiste/sma_chg_log/internal/credentials"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store the password of the device in the keyring",
+	Long: "Verify the password with the device and store it per host and username in the keyring selected with --keyring, " +
+		"so later runs need no password in the environment. The password is read from --password-stdin, --password-file " +
+		"or --password, or prompted for on the terminal.",
+	PersistentPreRunE: persistentPreRunHost,
+	RunE:              runLogin,
+}
+
+func init() {
+	loginCmd.Flags().Bool("delete", false, "Delete the stored password instead")
+	loginCmd.Flags().Bool("no-verify", false, "Store the password without verifying it with the device")
+	must(viper.BindPFlags(loginCmd.Flags()))
+
+	rootCmd.AddCommand(loginCmd)
+}
+
+func runLogin(cmd *cobra.Command, args []string) error {
+	store, err := openCredentialStore()
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return errors.New("login needs a keyring, select one with --keyring system or --keyring file")
+	}
+
+	out := cmd.OutOrStdout()
+	if viper.GetBool("delete") {
+		if err := store.Delete(cfg.Host, cfg.Username); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "Deleted password of %s on %s from the %s\n", cfg.Username, cfg.Host, store)
+		return err
+	}
+
+	// a stored password is replaced, so it is not looked up
+	cfg.Keyring = credentials.BackendNone
+	if err := cfg.resolvePassword(); err != nil {
+		return err
+	}
+	if cfg.Password == "" {
+		if cfg.Password, err = promptPassword(); err != nil {
+			return err
+		}
+	}
+
+	if !viper.GetBool("no-verify") {
+		apiClient, err := newClient()
+		if err != nil {
+			return err
+		}
+		if err := apiClient.Login(cmd.Context()); err != nil {
+			return fmt.Errorf("failed to log in: %w", err)
+		}
+	}
+
+	if err := store.Set(cfg.Host, cfg.Username, cfg.Password); err != nil {
+		return fmt.Errorf("failed to store password in the %s: %w", store, err)
+	}
+	_, err = fmt.Fprintf(out, "Stored password of %s on %s in the %s\n", cfg.Username, cfg.Host, store)
+	return err
+}
+
+// promptPassword reads the password from the terminal without echo
+func promptPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("password is required (use --password, --password-file or --password-stdin)")
+	}
+	_, _ = fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(password) == 0 {
+		return "", errors.New("empty password")
+	}
+	return string(password), nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Revoke and delete the cached token of the device",
+	Long:  "Revoke the refresh token cached with --token-cache on the device and delete the cache file.",
+	// only host and username identify the cached token, so the root's validation is skipped
+	PersistentPreRunE: persistentPreRunHost,
+	RunE:              runLogout,
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+}
+
+func runLogout(cmd *cobra.Command, args []string) error {
+	cfg.TokenCache = true
+	apiClient, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if err := apiClient.Logout(cmd.Context()); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Logged out of %s\n", cfg.Host)
+	return err
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// outputFile is written to a temporary file next to its path, which replaces the path only when the run succeeds,
+// so a failed run neither truncates a previous report nor leaves a partially written one
+type outputFile struct {
+	*os.File
+	path string
+	// direct outputs are written in place, e.g. /dev/null or a named pipe
+	direct bool
+}
+
+// outputs are the output files created by this run, committed by commitOutputs or removed by discardOutputs
+var outputs []*outputFile
+
+// createOutput creates an output file that replaces path when the run succeeds
+func createOutput(path string) (*outputFile, error) {
+	info, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil && !info.Mode().IsRegular() {
+		if info.IsDir() {
+			return nil, fmt.Errorf("output %s is a directory", path)
+		}
+		// devices and pipes cannot be replaced, nor are they truncated
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		out := &outputFile{File: f, path: path, direct: true}
+		outputs = append(outputs, out)
+		return out, nil
+	}
+
+	if err := checkClobber(path); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output %s: %w", path, err)
+	}
+	out := &outputFile{File: tmp, path: path}
+	outputs = append(outputs, out)
+	return out, nil
+}
+
+// checkClobber rejects existing outputs with --no-clobber (unless --force is given)
+func checkClobber(path string) error {
+	if !cfg.NoClobber || cfg.Force {
+		return nil
+	}
+	_, err := os.Lstat(path)
+	if err == nil {
+		return fmt.Errorf("output %s already exists (use --force to overwrite)", path)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// commit moves the temporary file to the path, keeping the mode of a replaced file
+func (o *outputFile) commit() error {
+	err := o.Close()
+	if o.direct {
+		return err
+	}
+
+	if err == nil {
+		// the file may have been created meanwhile
+		err = checkClobber(o.path)
+	}
+	if err == nil {
+		mode := fs.FileMode(0o644)
+		if info, statErr := os.Stat(o.path); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+		err = os.Chmod(o.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(o.Name(), o.path)
+	}
+	if err != nil {
+		_ = os.Remove(o.Name())
+		return fmt.Errorf("failed to write output %s: %w", o.path, err)
+	}
+	return nil
+}
+
+// commitOutputs moves the outputs of a successful run to their paths
+func commitOutputs() error {
+	var errs []error
+	for _, out := range outputs {
+		errs = append(errs, out.commit())
+	}
+	outputs = nil
+	return errors.Join(errs...)
+}
+
+// discardOutputs removes the (partially written) temporary files of a failed run, leaving existing outputs untouched
+func discardOutputs() {
+	for _, out := range outputs {
+		_ = out.Close()
+		if out.direct {
+			continue
+		}
+		if err := os.Remove(out.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("failed to remove incomplete output", "path", out.Name(), "error", err)
+		}
+	}
+	outputs = nil
+}
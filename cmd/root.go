@@ -1,66 +1,137 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/joshiste/sma_chg_log/internal/models"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/joshiste/sma_chg_log/internal/client"
+	"github.com/joshiste/sma_chg_log/internal/credentials"
 	"github.com/joshiste/sma_chg_log/internal/log"
+	"github.com/joshiste/sma_chg_log/internal/timerange"
 )
 
 var cfg = Config{}
 
 type Config struct {
-	Host     string
-	Username string
-	Password string
-	Format   string
-	Writer   io.Writer
-	From     time.Time
-	Until    time.Time
+	Host              string
+	Devices           []Device
+	DeviceFlags       []string `mapstructure:"device"`
+	Username          string
+	Password          string
+	PasswordFile      string `mapstructure:"password-file"`
+	PasswordStdin     bool   `mapstructure:"password-stdin"`
+	Keyring           string
+	KeyringFile       string `mapstructure:"keyring-file"`
+	KeyringPassphrase string `mapstructure:"keyring-passphrase"`
+	Insecure          bool
+	CACert            string `mapstructure:"ca-cert"`
+	// TLSFingerprint pins the device certificate by its SHA-256 fingerprint
+	TLSFingerprint string   `mapstructure:"tls-fingerprint"`
+	RedactFields   []string `mapstructure:"redact-fields"`
+	Timeout        time.Duration
+	TotalTimeout   time.Duration `mapstructure:"total-timeout"`
+	MaxAttempts    int           `mapstructure:"max-attempts"`
+	RetryDelay     time.Duration `mapstructure:"retry-delay"`
+	ServerFilter   bool          `mapstructure:"server-filter"`
+	GroupTags      []int         `mapstructure:"message-group-tags"`
+	TokenCache     bool          `mapstructure:"token-cache"`
+	Record         string
+	Replay         string
+	Format         string
+	Input          []string
+	Archive        []string
+	Timezone       string
+	Location       *time.Location `mapstructure:"-"`
+	Output         string
+	NoClobber      bool `mapstructure:"no-clobber"`
+	Force          bool
+	SplitBy        string `mapstructure:"split-by"`
+	Writer         io.Writer
+	From           time.Time `mapstructure:"-"`
+	Until          time.Time `mapstructure:"-"`
+
+	passwordResolved bool
+	devicesResolved  bool
 }
 
 func (c *Config) Validate() error {
 	var errs []error
 
-	if c.Host == "" {
-		errs = append(errs, errors.New("host is required (use --host flag or SMA_HOST environment variable)"))
+	if len(c.Input) > 0 && len(c.Archive) > 0 {
+		errs = append(errs, errors.New("--input and --archive are mutually exclusive"))
 	}
 
-	if !strings.HasPrefix(c.Host, "http://") && !strings.HasPrefix(c.Host, "https://") {
-		c.Host = "https://" + c.Host
+	if c.Record != "" && c.Replay != "" {
+		errs = append(errs, errors.New("--record and --replay are mutually exclusive"))
 	}
 
-	if c.Username == "" {
-		errs = append(errs, errors.New("username is required (use --username flag or SMA_USERNAME environment variable)"))
+	if (len(c.Input) > 0 || len(c.Archive) > 0) && (c.Record != "" || c.Replay != "") {
+		errs = append(errs, errors.New("--record and --replay cannot be combined with --input or --archive"))
 	}
 
-	if c.Password == "" {
-		errs = append(errs, errors.New("password is required (use --password flag or SMA_PASSWORD environment variable)"))
+	if (c.Record != "" || c.Replay != "") && c.multiDevice() {
+		errs = append(errs, errors.New("--record and --replay support a single device only"))
+	}
+
+	if c.Record != "" {
+		if err := client.ValidateRecordDir(c.Record); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if month := viper.GetString("month"); month != "" {
-		parsed, err := time.Parse("2006-01", month)
-		if err != nil {
-			errs = append(errs, errors.New("month must be in format YYYY-MM"))
+	// a replay needs neither the device nor its credentials
+	if len(c.Input) == 0 && len(c.Archive) == 0 && c.Replay == "" {
+		errs = append(errs, c.validateDevice()...)
+	}
+
+	c.Location = time.Local
+	if c.Timezone != "" {
+		if loc, err := time.LoadLocation(c.Timezone); err == nil {
+			c.Location = loc
 		} else {
-			c.From = parsed.UTC()
-			c.Until = parsed.AddDate(0, 1, 0).UTC()
+			errs = append(errs, fmt.Errorf("invalid timezone %q: %w", c.Timezone, err))
+		}
+	}
+
+	spec := timerange.Spec{
+		From:    viper.GetString("from"),
+		Until:   viper.GetString("until"),
+		Month:   viper.GetString("month"),
+		Year:    viper.GetString("year"),
+		Quarter: viper.GetString("quarter"),
+		Period:  viper.GetString("period"),
+	}
+	if viper.GetBool("last-month") {
+		if spec.Period != "" {
+			errs = append(errs, errors.New("--last-month cannot be combined with --period"))
 		}
+		spec.Period = "last-month"
+	}
+	if r, err := spec.Resolve(time.Now(), c.Location); err == nil {
+		c.From = r.From
+		c.Until = r.Until
 	} else {
-		c.Until = models.TimeMax
+		errs = append(errs, err)
 	}
 
-	if output := viper.GetString("output"); output == "-" {
+	if c.SplitBy != "" {
+		// one output per group is created when writing, see writeSplitSessions
+		if err := validateSplitOutput(c.SplitBy, c.Output); err != nil {
+			errs = append(errs, err)
+		}
+	} else if c.Output == "-" {
 		c.Writer = os.Stdout
-	} else if f, err := os.Create(output); err == nil {
+	} else if f, err := createOutput(c.Output); err == nil {
 		c.Writer = f
 	} else {
 		errs = append(errs, err)
@@ -69,6 +140,45 @@ func (c *Config) Validate() error {
 	return errors.Join(errs...)
 }
 
+// validateDevice checks the options needed to connect to the device
+func (c *Config) validateDevice() []error {
+	var errs []error
+
+	if c.multiDevice() {
+		errs = append(errs, c.validateDevices()...)
+	} else {
+		if c.Host == "" {
+			errs = append(errs, errors.New("host is required (use --host flag or SMA_HOST environment variable)"))
+		}
+
+		c.Host = normalizeHost(c.Host)
+
+		if c.Username == "" {
+			errs = append(errs, errors.New("username is required (use --username flag or SMA_USERNAME environment variable)"))
+		}
+
+		if err := c.resolvePassword(); err != nil {
+			errs = append(errs, err)
+		} else if c.Password == "" {
+			errs = append(errs, errors.New("password is required (use --password, --password-file, --password-stdin, SMA_PASSWORD or the login command)"))
+		}
+	}
+
+	if c.MaxAttempts < 1 {
+		errs = append(errs, errors.New("max-attempts must be at least 1"))
+	}
+
+	return errs
+}
+
+// normalizeHost defaults the scheme of the host to https
+func normalizeHost(host string) string {
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		return "https://" + host
+	}
+	return host
+}
+
 var rootCmd = &cobra.Command{
 	Use:                "sma_chg_log",
 	Short:              "Fetch event messages from ennexos device",
@@ -81,13 +191,44 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global persistent flags (available to all subcommands)
+	rootCmd.PersistentFlags().String("config", "", "Config file (default: ~/.config/sma_chg_log/config.yaml)")
+	rootCmd.PersistentFlags().StringP("profile", "P", "", "Profile of the config file to use")
 	rootCmd.PersistentFlags().StringP("host", "H", "", "Hostname of the SMA device")
+	rootCmd.PersistentFlags().StringArray("device", nil, "Device to fetch from instead of --host, repeatable for several devices (format: [name=][username@]host)")
 	rootCmd.PersistentFlags().StringP("username", "u", "", "Username for authentication")
 	rootCmd.PersistentFlags().StringP("password", "p", "", "Password for authentication")
+	rootCmd.PersistentFlags().String("password-file", "", "Read the password from the first line of a file")
+	rootCmd.PersistentFlags().Bool("password-stdin", false, "Read the password from the first line of stdin")
+	rootCmd.PersistentFlags().String("keyring", credentials.BackendNone, "Keyring of passwords stored with the login command: "+strings.Join(credentials.Backends, ", "))
+	rootCmd.PersistentFlags().String("keyring-file", "", "Encrypted password file of the file keyring (default: ~/.config/sma_chg_log/credentials.enc)")
+	rootCmd.PersistentFlags().StringArrayP("input", "i", nil, "Read events from a file exported by the events command instead of the device ('-' for stdin), repeatable to merge several files")
+	rootCmd.PersistentFlags().StringArray("archive", nil, "Local event archive written by the sync command; sessions and events read from it instead of the device, repeatable to merge several archives")
+	rootCmd.PersistentFlags().Duration("timeout", 30*time.Second, "Timeout of each request to the device (0 for none)")
+	rootCmd.PersistentFlags().Duration("total-timeout", 0, "Timeout of the whole run, e.g. 10m (0 for none)")
+	rootCmd.PersistentFlags().Int("max-attempts", client.DefaultRetryPolicy.MaxAttempts, "Maximum attempts per request on transient errors (1 disables retries)")
+	rootCmd.PersistentFlags().Duration("retry-delay", client.DefaultRetryPolicy.BaseDelay, "Delay before the first retry, doubled for each further retry")
+	rootCmd.PersistentFlags().Bool("server-filter", true, "Let the device filter messages by time range (falls back to local filtering if unsupported)")
+	rootCmd.PersistentFlags().IntSlice("message-group-tags", nil, "Only fetch messages of these message groups (see messageGroupTag in the events output)")
+	rootCmd.PersistentFlags().Bool("token-cache", false, "Keep the access token between runs in a user-private file instead of logging in every run (see logout command)")
+	rootCmd.PersistentFlags().Bool("insecure", false, "Skip verification of the device's TLS certificate")
+	rootCmd.PersistentFlags().String("ca-cert", "", "PEM file with CA certificates to verify the device's TLS certificate")
+	rootCmd.PersistentFlags().String("tls-fingerprint", "", "Trust the device's TLS certificate by its SHA-256 fingerprint (see trust command)")
+	rootCmd.PersistentFlags().String("record", "", "Record the device traffic with credentials redacted to a directory, e.g. for bug reports")
+	rootCmd.PersistentFlags().String("replay", "", "Replay device traffic recorded with --record instead of contacting the device")
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Log level: trace, debug, info, warn, error")
+	rootCmd.PersistentFlags().StringSlice("redact-fields", client.DefaultRedactFields, "Request/response body fields redacted from trace logs and recordings (password is always redacted)")
 	rootCmd.PersistentFlags().StringP("month", "m", "", "Filter by month (format: YYYY-MM)")
-	rootCmd.PersistentFlags().StringP("format", "f", "json", "Output format: json, csv, or pdf")
+	rootCmd.PersistentFlags().String("year", "", "Filter by year (format: YYYY)")
+	rootCmd.PersistentFlags().String("quarter", "", "Filter by quarter (format: YYYY-QN, e.g. 2026-Q2)")
+	rootCmd.PersistentFlags().String("from", "", "Filter from date, inclusive (format: YYYY-MM-DD)")
+	rootCmd.PersistentFlags().String("until", "", "Filter until date, inclusive (format: YYYY-MM-DD)")
+	rootCmd.PersistentFlags().String("period", "", "Filter by relative period: "+strings.Join(timerange.Keywords, ", "))
+	rootCmd.PersistentFlags().Bool("last-month", false, "Filter by the previous calendar month (shortcut for --period last-month)")
+	rootCmd.PersistentFlags().String("timezone", "", "Timezone for date boundaries and timestamps, e.g. Europe/Berlin (default: system timezone)")
+	rootCmd.PersistentFlags().StringP("format", "f", "json", "Output format: json, csv, pdf, or xlsx")
 	rootCmd.PersistentFlags().StringP("output", "o", "-", "Output file path (use '-' for stdout)")
+	rootCmd.PersistentFlags().Bool("no-clobber", false, "Fail instead of overwriting an existing output file")
+	rootCmd.PersistentFlags().Bool("force", false, "Overwrite existing output files even with --no-clobber (e.g. set in the config file)")
 
 	must(viper.BindPFlags(rootCmd.PersistentFlags()))
 }
@@ -102,13 +243,16 @@ func initConfig() {
 	viper.SetEnvPrefix("SMA")
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	must(viper.BindEnv("password"))
+	must(viper.BindEnv("keyring-passphrase"))
 	viper.AutomaticEnv()
 
+	configErr = loadConfigFile()
+
 	log.Init()
 }
 
 func persistentPreRunE(cmd *cobra.Command, args []string) error {
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := unmarshalConfig(); err != nil {
 		return err
 	}
 
@@ -116,18 +260,47 @@ func persistentPreRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if cfg.TotalTimeout > 0 {
+		ctx, cancel := context.WithTimeout(cmd.Context(), cfg.TotalTimeout)
+		cancelTotalTimeout = cancel
+		cmd.SetContext(ctx)
+	}
+
 	return nil
 }
 
-func persistentPostRunE(cmd *cobra.Command, args []string) error {
-	if f, ok := cfg.Writer.(io.Closer); ok && f != os.Stdout && f != os.Stderr {
-		_ = f.Close()
+// persistentPreRunHost replaces persistentPreRunE for commands that only need the host and username
+func persistentPreRunHost(cmd *cobra.Command, args []string) error {
+	if err := unmarshalConfig(); err != nil {
+		return err
 	}
-	return nil
+	var errs []error
+	if cfg.Host == "" {
+		errs = append(errs, errors.New("host is required (use --host flag or SMA_HOST environment variable)"))
+	}
+	if cfg.Username == "" {
+		errs = append(errs, errors.New("username is required (use --username flag or SMA_USERNAME environment variable)"))
+	}
+	cfg.Host = normalizeHost(cfg.Host)
+	return errors.Join(errs...)
 }
 
+func persistentPostRunE(cmd *cobra.Command, args []string) error {
+	return commitOutputs()
+}
+
+// cancelTotalTimeout releases the context of the total timeout (if set)
+var cancelTotalTimeout context.CancelFunc = func() {}
+
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	// Interrupting cancels in-flight requests; the incomplete output is discarded below
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTotalTimeout()
+	stop()
+
+	if err != nil {
+		discardOutputs()
 		os.Exit(-1)
 	}
 }
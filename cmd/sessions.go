@@ -3,17 +3,21 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/joshiste/sma_chg_log/internal/client"
+	"github.com/joshiste/sma_chg_log/internal/authmap"
+	"github.com/joshiste/sma_chg_log/internal/i18n"
 	"github.com/joshiste/sma_chg_log/internal/models"
 	"github.com/joshiste/sma_chg_log/internal/output"
+	"github.com/joshiste/sma_chg_log/internal/pairing"
+	"github.com/joshiste/sma_chg_log/internal/tariff"
 )
 
 var mapAuthenticationRaw []string
@@ -27,6 +31,22 @@ var sessionsCmd = &cobra.Command{
 
 func init() {
 	sessionsCmd.Flags().StringArrayVarP(&mapAuthenticationRaw, "map-authentication", "a", nil, "Map authentication values (format: old:new, can be specified multiple times)")
+	sessionsCmd.Flags().String("auth-map", "", "Authentication mapping file (.yaml or .csv) with display name and user metadata per authentication")
+	sessionsCmd.Flags().String("attribution", "end", "Attribution of sessions spanning the range boundary: end, start, or split (pro-rata by duration)")
+	sessionsCmd.Flags().String("tariff", "", "Tariff for cost calculation: price per kWh (e.g. 0.30), YAML tariff file or CSV file with dynamic prices")
+	sessionsCmd.Flags().String("currency", "EUR", "Currency of the tariff prices")
+	sessionsCmd.Flags().Float64("cost-rounding", 0.01, "Round costs to multiples of this amount (e.g. 0.01 or 0.05)")
+	sessionsCmd.Flags().String("split-by", "", "Write one output per group: authentication or charger (output must be a filename template, e.g. report-{month}-{auth}.pdf)")
+	sessionsCmd.Flags().String("lang", i18n.DefaultLanguage, "Language of the PDF and XLSX reports and the CSV header, the CSV status and anomaly values are stable machine values: "+strings.Join(i18n.Languages, ", "))
+	sessionsCmd.Flags().String("csv-dialect", "default", "CSV dialect preset: default, excel-en, excel-de (adjusted by the other csv flags)")
+	sessionsCmd.Flags().String("csv-delimiter", "", "CSV field delimiter, e.g. ';' or 'tab' (default: from the dialect)")
+	sessionsCmd.Flags().String("csv-decimal", "", "CSV decimal separator: '.' or ',' (default: from the dialect)")
+	sessionsCmd.Flags().String("csv-date-layout", "", "CSV record date layout in Go notation, e.g. 02.01.2006 (default: from the dialect)")
+	sessionsCmd.Flags().String("csv-time-layout", "", "CSV start and end time layout in Go notation, e.g. '02.01.2006 15:04' (default: from the dialect)")
+	sessionsCmd.Flags().String("csv-header-lang", "", "CSV header language, the status and anomaly values stay in English: en, de (default: from the dialect)")
+	sessionsCmd.Flags().Bool("csv-bom", false, "Start the CSV with a UTF-8 byte order mark, so Excel detects the encoding (default: from the dialect)")
+	sessionsCmd.Flags().String("csv-quote", "", "CSV quoting: minimal (only where needed) or all (default: from the dialect)")
+	sessionsCmd.Flags().Duration("boundary-margin", 72*time.Hour, "Margin fetched before and after the range to pair sessions spanning its boundary")
 	must(viper.BindPFlags(sessionsCmd.Flags()))
 
 	rootCmd.AddCommand(sessionsCmd)
@@ -34,30 +54,84 @@ func init() {
 	rootCmd.RunE = runSessions
 }
 
-func parseMapAuthentication(raw []string) map[string]string {
-	result := make(map[string]string)
-	for _, entry := range raw {
-		parts := strings.SplitN(entry, ":", 2)
-		if len(parts) == 2 {
-			result[parts[0]] = parts[1]
-		}
+// loadAuthenticationMapping combines the mapping flags (or the map-authentication setting) with the
+// optional mapping file; the flags take precedence
+func loadAuthenticationMapping() (*authmap.Mapping, bool, error) {
+	raw := mapAuthenticationRaw
+	if len(raw) == 0 {
+		// viper splits flag values at commas, so it is only asked for the setting of the config file
+		raw = viper.GetStringSlice("map-authentication")
+	}
+	entries, err := authmap.ParseFlags(raw)
+	if err != nil {
+		return nil, false, err
+	}
+
+	path := viper.GetString("auth-map")
+	if path == "" {
+		return authmap.New(entries...), false, nil
+	}
+
+	fromFile, err := authmap.Load(path, cfg.Location)
+	if err != nil {
+		return nil, false, err
 	}
-	return result
+	return authmap.New(append(entries, fromFile.Entries()...)...), true, nil
 }
 
 func runSessions(cmd *cobra.Command, args []string) error {
-	if cfg.Format != "" && cfg.Format != "json" && cfg.Format != "csv" && cfg.Format != "pdf" {
-		return errors.New("format must be 'json', 'csv', or 'pdf'")
+	if cfg.Format != "" && cfg.Format != "json" && cfg.Format != "csv" && cfg.Format != "pdf" && cfg.Format != "xlsx" {
+		return errors.New("format must be 'json', 'csv', 'pdf', or 'xlsx'")
+	}
+
+	attribution, err := pairing.ParseAttribution(viper.GetString("attribution"))
+	if err != nil {
+		return err
+	}
+
+	locale, err := i18n.Lookup(viper.GetString("lang"))
+	if err != nil {
+		return err
+	}
+
+	var dialect output.CSVDialect
+	if cfg.Format == "csv" {
+		if dialect, err = csvDialect(); err != nil {
+			return err
+		}
+	}
+
+	var costTariff tariff.Tariff
+	if definition := viper.GetString("tariff"); definition != "" {
+		if costTariff, err = tariff.Load(definition, cfg.Location); err != nil {
+			return err
+		}
 	}
 
-	authMap := parseMapAuthentication(mapAuthenticationRaw)
-	slog.Debug("Authentication mapping", "map", authMap)
+	authMapping, userColumns, err := loadAuthenticationMapping()
+	if err != nil {
+		return err
+	}
+	slog.Debug("Authentication mapping", "entries", len(authMapping.Entries()))
+
+	messageSource, err := newSource()
+	if err != nil {
+		return err
+	}
 
-	apiClient := client.New(cfg.Host, cfg.Username, cfg.Password)
+	// Fetch a margin around the range, so sessions spanning its boundary are paired completely
+	margin := viper.GetDuration("boundary-margin")
+	fetchFrom, fetchUntil := cfg.From, cfg.Until
+	if !fetchFrom.IsZero() {
+		fetchFrom = fetchFrom.Add(-margin)
+	}
+	if !fetchUntil.Equal(models.TimeMax) {
+		fetchUntil = fetchUntil.Add(margin)
+	}
 
 	// Collect all messages for pairing
 	var allMessages []models.Message
-	err := apiClient.FetchAllMessages(cfg.From, cfg.Until, func(messages []models.Message) bool {
+	err = messageSource.FetchAllMessages(cmd.Context(), fetchFrom, fetchUntil, func(messages []models.Message) bool {
 		allMessages = append(allMessages, filterMessages(messages)...)
 		return true
 	})
@@ -66,24 +140,54 @@ func runSessions(cmd *cobra.Command, args []string) error {
 	}
 
 	// Pair messages into sessions and output
-	sessions := pairChargingSessions(allMessages, authMap)
+	var pairingOpts pairing.Options
+	if now := time.Now(); len(cfg.Input) == 0 && len(cfg.Archive) == 0 && cfg.Until.After(now) {
+		// the fetched messages are up to date, so open sessions are still charging
+		pairingOpts.Now = now
+	}
+	sessions := pairing.PairWithOptions(allMessages, pairingOpts)
+	sessions = attribution.Attribute(sessions, cfg.From, cfg.Until)
+	authMapping.Apply(sessions)
+
+	if costTariff != nil {
+		applyCosts(sessions, costTariff, attribution, cfg.From, cfg.Until, viper.GetString("currency"), viper.GetFloat64("cost-rounding"))
+	}
 
 	// Calculate date range from sessions if not explicitly set
 	opts := output.Options{
-		From:  cfg.From,
-		Until: cfg.Until,
+		From:        cfg.From,
+		Until:       cfg.Until,
+		Location:    cfg.Location,
+		Attribution: attribution,
+		UserColumns: userColumns,
+		// sessions of several devices, fetched or read from their exports and archives
+		DeviceColumn: slices.ContainsFunc(sessions, func(s models.ChargingSession) bool { return s.Device != "" }),
+		CSV:          dialect,
+		Locale:       locale,
+	}
+	if costTariff != nil {
+		opts.Currency = viper.GetString("currency")
+		opts.CostDecimals = tariff.Decimals(viper.GetFloat64("cost-rounding"))
 	}
 	if len(sessions) > 0 && opts.From.IsZero() {
-		opts.From = toDate(sessions[len(sessions)-1].End)
+		opts.From = toDate(attribution.RecordTime(sessions[len(sessions)-1], cfg.From, cfg.Until), cfg.Location)
 	}
 	if len(sessions) > 0 && opts.Until.Equal(models.TimeMax) {
-		opts.Until = toDate(sessions[0].End).AddDate(0, 0, 1)
+		opts.Until = toDate(attribution.RecordTime(sessions[0], cfg.From, cfg.Until), cfg.Location).AddDate(0, 0, 1)
 	}
 	if time.Now().Before(opts.Until) {
-		opts.Until = toDate(time.Now()).AddDate(0, 0, 1)
+		opts.Until = toDate(time.Now(), cfg.Location).AddDate(0, 0, 1)
 	}
 
-	formatter := output.NewSessionFormatterWithOptions(cfg.Format, cfg.Writer, opts)
+	if cfg.SplitBy != "" {
+		return writeSplitSessions(sessions, opts)
+	}
+	return writeSessions(cfg.Writer, sessions, opts)
+}
+
+// writeSessions writes the sessions in the configured format
+func writeSessions(w io.Writer, sessions []models.ChargingSession, opts output.Options) error {
+	formatter := output.NewSessionFormatterWithOptions(cfg.Format, w, opts)
 
 	if err := formatter.WriteHeader(); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
@@ -98,67 +202,28 @@ func runSessions(cmd *cobra.Command, args []string) error {
 	return formatter.Flush()
 }
 
-func toDate(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+// toDate truncates t to midnight of its calendar day in loc
+func toDate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
 }
 
-// pairChargingSessions pairs charging stopped events with their preceding started events
-// Messages are ordered newest to oldest, so a stopped event at index i may pair with started at i+1
-func pairChargingSessions(messages []models.Message, authMap map[string]string) []models.ChargingSession {
-	var sessions []models.ChargingSession
-
-	for i := 0; i < len(messages); i++ {
-		msg := messages[i]
-
-		// Only process charging completed events
-		if msg.MessageID != messageIDChargingCompleted {
+// applyCosts calculates the cost of all sessions with known consumption, priced over the part of
+// each session attributed to the range. Sessions without prices for their time keep an unknown cost.
+func applyCosts(sessions []models.ChargingSession, t tariff.Tariff, attribution pairing.Attribution, from, until time.Time, currency string, rounding float64) {
+	for i := range sessions {
+		if sessions[i].Status == models.StatusOngoing {
 			continue
 		}
-
-		session := models.ChargingSession{
-			ChargerName: msg.DeviceName,
-			Consumption: findConsumption(msg.Arguments),
-			End:         msg.Timestamp,
-		}
-
-		// Look for preceding charging started event (next in array since newest first)
-		if i+1 < len(messages) && messages[i+1].MessageID == messageIDChargingStarted {
-			startMsg := messages[i+1]
-			auth := findAuthentication(startMsg.Arguments)
-			session.Authentication = auth
-			session.Start = startMsg.Timestamp
-			i++ // Skip the start message since it's now paired
-		}
-
-		// Apply authorization mapping if configured
-		if mapped, ok := authMap[session.Authentication]; ok {
-			session.Authentication = mapped
-		}
-
-		sessions = append(sessions, session)
-	}
-
-	return sessions
-}
-
-// findConsumption finds the consumption value from message arguments
-func findConsumption(args []models.MessageArgument) float64 {
-	for _, arg := range args {
-		if arg.UnitTag == 8 && arg.DisplayType == "Fix2" {
-			if val, err := strconv.ParseFloat(arg.Value, 64); err == nil {
-				return val
-			}
-		}
-	}
-	return 0
-}
-
-// findAuthentication finds the authentication value from message arguments
-func findAuthentication(args []models.MessageArgument) string {
-	for _, arg := range args {
-		if arg.DisplayType == "String" && arg.Position == 0 {
-			return arg.Value
+		priced := sessions[i]
+		priced.Start, priced.End = attribution.Interval(priced, from, until)
+		cost, err := tariff.Cost(t, priced)
+		if err != nil {
+			slog.Warn("cost of session unknown", "charger", sessions[i].ChargerName, "start", sessions[i].Start, "end", sessions[i].End, "error", err)
+			continue
 		}
+		cost = tariff.Round(cost, rounding)
+		sessions[i].Cost = &cost
+		sessions[i].Currency = currency
 	}
-	return ""
 }
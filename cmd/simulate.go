@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/joshiste/sma_chg_log/internal/client"
+	"github.com/joshiste/sma_chg_log/internal/simulator"
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Run a simulated ennexOS device",
+	Long: "Serve the token and message search API of an ennexOS device with generated charging sessions, " +
+		"so the tool can be tried without a charger. The credentials are taken from --username and --password (default: user/password).",
+	// the simulator needs no device or output, so the root's validation is skipped
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return unmarshalConfig()
+	},
+	RunE: runSimulate,
+}
+
+func init() {
+	simulateCmd.Flags().String("listen", "127.0.0.1:8443", "Address to listen on")
+	simulateCmd.Flags().Bool("plain", false, "Serve plain HTTP instead of HTTPS with a self-signed certificate")
+	simulateCmd.Flags().Int("days", 90, "Days of generated message history")
+	simulateCmd.Flags().Uint64("seed", 1, "Seed of the generated messages and injected failures")
+	simulateCmd.Flags().Int("page-size", 100, "Messages per search response")
+	simulateCmd.Flags().Duration("token-lifetime", 0, "Lifetime of issued tokens (0 for no expiry)")
+	simulateCmd.Flags().Float64("failure-rate", 0, "Fraction of search requests failing with 503")
+	simulateCmd.Flags().Bool("ignore-filter", false, "Ignore the time range of search requests, like older firmware")
+	must(viper.BindPFlags(simulateCmd.Flags()))
+
+	rootCmd.AddCommand(simulateCmd)
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	days := viper.GetInt("days")
+	if days < 1 {
+		return errors.New("days must be at least 1")
+	}
+
+	sim := simulator.NewWithOptions(simulator.Options{
+		Username:      cfg.Username,
+		Password:      cfg.Password,
+		Messages:      simulator.Generate(viper.GetUint64("seed"), time.Now(), days),
+		PageSize:      viper.GetInt("page-size"),
+		TokenLifetime: viper.GetDuration("token-lifetime"),
+		IgnoreFilter:  viper.GetBool("ignore-filter"),
+		FailureRate:   viper.GetFloat64("failure-rate"),
+		Seed:          viper.GetUint64("seed"),
+	})
+
+	listener, err := net.Listen("tcp", viper.GetString("listen"))
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: sim, ReadHeaderTimeout: 10 * time.Second}
+	out := cmd.OutOrStdout()
+	scheme := "http"
+	if !viper.GetBool("plain") {
+		host, _, err := net.SplitHostPort(listener.Addr().String())
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+		cert, err := simulator.SelfSignedCertificate("localhost", host)
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		listener = tls.NewListener(listener, server.TLSConfig)
+		scheme = "https"
+		_, _ = fmt.Fprintf(out, "Fingerprint: %s\n", client.FormatFingerprint(client.Fingerprint(cert.Leaf)))
+	}
+
+	url := scheme + "://" + listener.Addr().String()
+	_, _ = fmt.Fprintf(out, "Simulating %d messages at %s\n", len(sim.Messages()), url)
+	slog.Info("simulator listening", "url", url)
+
+	ctx := cmd.Context()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
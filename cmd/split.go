@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/output"
+)
+
+const (
+	splitByAuthentication = "authentication"
+	splitByCharger        = "charger"
+)
+
+// unsafeFilenameChars matches characters replaced in group names used in filenames
+var unsafeFilenameChars = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// validateSplitOutput checks the split mode and that the output template distinguishes the groups
+func validateSplitOutput(splitBy, template string) error {
+	var placeholder string
+	switch splitBy {
+	case splitByAuthentication:
+		placeholder = "{auth}"
+	case splitByCharger:
+		placeholder = "{charger}"
+	default:
+		return fmt.Errorf("split-by must be '%s' or '%s'", splitByAuthentication, splitByCharger)
+	}
+
+	if template == "-" {
+		return errors.New("split-by requires an output filename template, not stdout")
+	}
+	if !strings.Contains(template, placeholder) && !strings.Contains(template, "{group}") {
+		return fmt.Errorf("output filename template must contain %s or {group} when splitting by %s", placeholder, splitBy)
+	}
+	return nil
+}
+
+// groupSessions groups sessions by authentication or charger, keeping the order of first occurrence
+func groupSessions(sessions []models.ChargingSession, splitBy string) ([]string, map[string][]models.ChargingSession) {
+	var keys []string
+	groups := make(map[string][]models.ChargingSession)
+	for _, session := range sessions {
+		key := session.Authentication
+		if splitBy == splitByCharger {
+			key = session.ChargerName
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], session)
+	}
+	return keys, groups
+}
+
+// expandOutputTemplate fills the placeholders of the output filename template
+func expandOutputTemplate(template, splitBy, group string, opts output.Options) string {
+	name := unsafeFilenameChars.ReplaceAllString(group, "_")
+	if name == "" || strings.Trim(name, "._") == "" {
+		name = "unknown"
+	}
+
+	auth, charger := "", ""
+	if splitBy == splitByCharger {
+		charger = name
+	} else {
+		auth = name
+	}
+
+	until := opts.Until
+	if !until.Equal(models.TimeMax) {
+		until = until.AddDate(0, 0, -1) // until is exclusive
+	}
+
+	return strings.NewReplacer(
+		"{group}", name,
+		"{auth}", auth,
+		"{charger}", charger,
+		"{month}", opts.From.In(cfg.Location).Format("2006-01"),
+		"{from}", opts.From.In(cfg.Location).Format("2006-01-02"),
+		"{until}", until.In(cfg.Location).Format("2006-01-02"),
+	).Replace(template)
+}
+
+// writeSplitSessions writes one output per group of sessions, each with its own summary
+func writeSplitSessions(sessions []models.ChargingSession, opts output.Options) error {
+	keys, groups := groupSessions(sessions, cfg.SplitBy)
+
+	filenames := make(map[string]string)
+	for _, key := range keys {
+		filename := expandOutputTemplate(cfg.Output, cfg.SplitBy, key, opts)
+		if other, ok := filenames[filename]; ok {
+			return fmt.Errorf("groups %q and %q would both be written to %s", other, key, filename)
+		}
+		filenames[filename] = key
+	}
+
+	for _, key := range keys {
+		filename := expandOutputTemplate(cfg.Output, cfg.SplitBy, key, opts)
+
+		// the outputs replace their files once all are written, see commitOutputs
+		f, err := createOutput(filename)
+		if err != nil {
+			return err
+		}
+		if err := writeSessions(f, groups[key], opts); err != nil {
+			return fmt.Errorf("failed to write %s: %w", filename, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/joshiste/sma_chg_log/internal/archive"
+	"github.com/joshiste/sma_chg_log/internal/client"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Store new device messages in the local archive",
+	Long: "Fetch the messages newer than the last archived one from the device and append them to the local archive, " +
+		"so the history survives the device's log rotation. Use --archive with the sessions and events commands to read from the archive.",
+	RunE: runSync,
+}
+
+func init() {
+	syncCmd.Flags().Bool("full", false, "Fetch the complete history instead of stopping at the last archived message")
+	must(viper.BindPFlags(syncCmd.Flags()))
+
+	rootCmd.AddCommand(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	if len(cfg.Input) > 0 {
+		return errors.New("sync cannot be combined with --input")
+	}
+	if len(cfg.Archive) > 1 {
+		return errors.New("sync writes a single archive, --archive can only be given once")
+	}
+	path := ""
+	if len(cfg.Archive) > 0 {
+		path = cfg.Archive[0]
+	}
+	if errs := cfg.validateDevice(); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if !cfg.multiDevice() {
+		apiClient, err := newClient()
+		if err != nil {
+			return err
+		}
+		return syncArchive(cmd.Context(), apiClient, path, cfg.Host, "")
+	}
+
+	// each device has its own archive, its messages are tagged with the device name, so the archives
+	// can be merged into one report with several --archive
+	if path != "" && len(cfg.Devices) > 1 {
+		return errors.New("--archive cannot be combined with several devices, each device is synchronized to its default archive")
+	}
+	for _, device := range cfg.Devices {
+		apiClient, err := newDeviceClient(device)
+		if err != nil {
+			return fmt.Errorf("device %s: %w", device.Name, err)
+		}
+		if err := syncArchive(cmd.Context(), apiClient, path, device.Host, device.Name); err != nil {
+			return fmt.Errorf("device %s: %w", device.Name, err)
+		}
+	}
+	return nil
+}
+
+// syncArchive appends the new messages of the device to the archive at path (default: the host's archive),
+// tagged with the device name if given
+func syncArchive(ctx context.Context, apiClient *client.Client, path, host, name string) error {
+	if path == "" {
+		var err error
+		if path, err = archive.DefaultPath(host); err != nil {
+			return err
+		}
+	}
+	store := archive.Open(path)
+
+	added, total, err := store.Sync(ctx, apiClient, cfg.From, cfg.Until, archive.SyncOptions{Full: viper.GetBool("full"), Source: name})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("archive synchronized", "path", store.Path(), "added", added, "total", total)
+	_, err = fmt.Fprintf(cfg.Writer, "%d new messages archived in %s\n", added, store.Path())
+	return err
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joshiste/sma_chg_log/internal/client"
+)
+
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Print the fingerprint of the device's TLS certificate",
+	Long: "Connect to the device without verification and print its TLS certificate and SHA-256 fingerprint. " +
+		"After checking the fingerprint (e.g. against the device's web interface), pass it with --tls-fingerprint to trust the device.",
+	// only the host is needed, so the root's validation of credentials and output is skipped
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return unmarshalConfig()
+	},
+	RunE: runTrust,
+}
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+}
+
+func runTrust(cmd *cobra.Command, args []string) error {
+	if cfg.Host == "" {
+		return errors.New("host is required (use --host flag or SMA_HOST environment variable)")
+	}
+
+	addr, err := tlsAddress(cfg.Host)
+	if err != nil {
+		return err
+	}
+
+	certs, err := client.FetchCertificate(cmd.Context(), addr, cfg.Timeout)
+	if err != nil {
+		return err
+	}
+
+	leaf := certs[0]
+	fingerprint := client.FormatFingerprint(client.Fingerprint(leaf))
+
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "Subject:     %s\n", leaf.Subject)
+	_, _ = fmt.Fprintf(out, "Issuer:      %s\n", leaf.Issuer)
+	_, _ = fmt.Fprintf(out, "Valid:       %s - %s\n", leaf.NotBefore.Format("2006-01-02"), leaf.NotAfter.Format("2006-01-02"))
+	if len(leaf.DNSNames) > 0 || len(leaf.IPAddresses) > 0 {
+		names := leaf.DNSNames
+		for _, ip := range leaf.IPAddresses {
+			names = append(names, ip.String())
+		}
+		_, _ = fmt.Fprintf(out, "Names:       %s\n", strings.Join(names, ", "))
+	}
+	_, _ = fmt.Fprintf(out, "SHA-256:     %s\n\n", fingerprint)
+	_, err = fmt.Fprintf(out, "To trust this certificate use:\n  --tls-fingerprint %s\n", fingerprint)
+	return err
+}
+
+// tlsAddress returns host:port of the device, defaulting to port 443
+func tlsAddress(host string) (string, error) {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+	if u.Scheme != "https" {
+		return "", errors.New("trust requires an https host")
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
@@ -1,19 +1,107 @@
 package cmd
 
 import (
+	"path/filepath"
+	"strings"
+
+	"github.com/joshiste/sma_chg_log/internal/archive"
+	"github.com/joshiste/sma_chg_log/internal/client"
 	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/source"
 )
 
-const (
-	messageIDChargingCompleted = 9813
-	messageIDChargingStarted   = 9812
-)
+// newSource creates the message source: the input files or archives if given, the devices otherwise
+func newSource() (source.Source, error) {
+	if len(cfg.Input) > 0 {
+		return fileSource(cfg.Input, func(path string) source.Source { return source.NewFile(path) }), nil
+	}
+	if len(cfg.Archive) > 0 {
+		return fileSource(cfg.Archive, func(path string) source.Source { return archive.Open(path) }), nil
+	}
+	if cfg.multiDevice() {
+		return deviceSources()
+	}
+	return newClient()
+}
+
+// fileSource opens the files, several files are merged. Messages not tagged with their device
+// (e.g. archived by a sync of a single device) are tagged with the name of their file, which is
+// the device's host for default archives.
+func fileSource(paths []string, open func(path string) source.Source) source.Source {
+	if len(paths) == 1 {
+		return open(paths[0])
+	}
+	sources := make([]source.Named, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if path == "-" {
+			name = "stdin"
+		}
+		sources = append(sources, source.Named{Name: name, Source: open(path)})
+	}
+	return source.Merge(sources...)
+}
+
+// newClient creates the client for the configured device
+func newClient() (*client.Client, error) {
+	return newDeviceClient(Device{Host: cfg.Host, Username: cfg.Username, Password: cfg.Password})
+}
+
+// newDeviceClient creates the client for a device, its TLS settings default to the global ones
+func newDeviceClient(device Device) (*client.Client, error) {
+	tlsOptions := client.TLSOptions{
+		Insecure:    cfg.Insecure || device.Insecure,
+		CAFile:      cfg.CACert,
+		Fingerprint: cfg.TLSFingerprint,
+	}
+	if device.CACert != "" {
+		tlsOptions.CAFile = device.CACert
+	}
+	if device.TLSFingerprint != "" {
+		tlsOptions.Fingerprint = device.TLSFingerprint
+	}
+	tlsConfig, err := client.NewTLSConfig(tlsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	host := device.Host
+	if cfg.Replay != "" && host == "" {
+		// the host is not contacted, but requests need a valid URL
+		host = "https://replay.invalid"
+	}
+
+	var tokenCache *client.TokenCache
+	if cfg.TokenCache && cfg.Replay == "" {
+		path, err := client.DefaultTokenCachePath(device.Host, device.Username)
+		if err != nil {
+			return nil, err
+		}
+		tokenCache = client.NewTokenCache(path)
+	}
+
+	return client.NewWithOptions(host, device.Username, device.Password, client.Options{
+		TLS:          tlsConfig,
+		RedactFields: cfg.RedactFields,
+		Timeout:      cfg.Timeout,
+		Retry: client.RetryPolicy{
+			MaxAttempts: cfg.MaxAttempts,
+			BaseDelay:   cfg.RetryDelay,
+			MaxDelay:    client.DefaultRetryPolicy.MaxDelay,
+		},
+		DisableServerFilter: !cfg.ServerFilter,
+		MessageGroupTags:    cfg.GroupTags,
+		RecordDir:           cfg.Record,
+		ReplayDir:           cfg.Replay,
+		TokenCache:          tokenCache,
+	}), nil
+}
 
 // filterMessages filters messages by messageId (charging started/completed only)
 func filterMessages(messages []models.Message) []models.Message {
 	var filtered []models.Message
 	for _, msg := range messages {
-		if msg.MessageID == messageIDChargingCompleted || msg.MessageID == messageIDChargingStarted {
+		if msg.MessageID == models.MessageIDChargingCompleted || msg.MessageID == models.MessageIDChargingStarted {
 			filtered = append(filtered, msg)
 		}
 	}
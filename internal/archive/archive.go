@@ -0,0 +1,230 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/source"
+)
+
+// unsafeChars matches characters not used in archive filenames
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Archive is a local append-only store of raw messages in JSON lines format
+// (the same format as written by the events command)
+type Archive struct {
+	path string
+}
+
+// Open opens the archive at path; the file is created on the first append
+func Open(path string) *Archive {
+	return &Archive{path: path}
+}
+
+// DefaultPath returns the default archive path for a device host
+// ($XDG_DATA_HOME/sma_chg_log/<host>.jsonl, defaulting to ~/.local/share)
+func DefaultPath(host string) (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine archive directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return filepath.Join(dir, "sma_chg_log", unsafeChars.ReplaceAllString(host, "_")+".jsonl"), nil
+}
+
+// Path returns the path of the archive file
+func (a *Archive) Path() string {
+	return a.path
+}
+
+// Key identifies a message for deduplication
+func Key(msg models.Message) string {
+	return msg.Marker + "/" + strconv.Itoa(msg.MessageTag) + "/" + msg.Timestamp.UTC().Format(time.RFC3339Nano)
+}
+
+// Keys returns the keys of all archived messages
+func (a *Archive) Keys() (map[string]struct{}, error) {
+	messages, err := a.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{}, len(messages))
+	for _, msg := range messages {
+		keys[Key(msg)] = struct{}{}
+	}
+	return keys, nil
+}
+
+// Append appends the messages whose key is not in keys (as returned by Keys) and returns the number
+// of messages added. The keys of the added messages are added to keys.
+func (a *Archive) Append(messages []models.Message, keys map[string]struct{}) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		return 0, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	added := 0
+	for _, msg := range messages {
+		key := Key(msg)
+		if _, ok := keys[key]; ok {
+			continue
+		}
+		if err := encoder.Encode(&msg); err != nil {
+			_ = f.Close()
+			return added, fmt.Errorf("failed to write archive: %w", err)
+		}
+		keys[key] = struct{}{}
+		added++
+	}
+
+	err = w.Flush()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return added, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return added, nil
+}
+
+// SyncOptions contains options for Sync
+type SyncOptions struct {
+	// Full fetches the complete range instead of stopping at the first archived message
+	Full bool
+	// Source tags the new messages with the name of the device they are fetched from (if set)
+	Source string
+}
+
+// Sync appends the messages of src within the range that are not archived yet and returns the number of
+// messages added and archived in total. The new messages are only written once the fetch completed, so an
+// interrupted sync leaves no gap. Unless the archive is partial, the fetch stops at the first archived message.
+func (a *Archive) Sync(ctx context.Context, src source.Source, from, until time.Time, opts SyncOptions) (int, int, error) {
+	keys, err := a.Keys()
+	if err != nil {
+		return 0, 0, err
+	}
+	archived := len(keys)
+	partial, err := a.Partial()
+	if err != nil {
+		return 0, archived, err
+	}
+	// stopping at the first archived message only skips archived messages if all older ones are archived as well
+	stopEarly := !opts.Full && !partial
+
+	var newMessages []models.Message
+	caughtUp := false
+	err = src.FetchAllMessages(ctx, from, until, func(messages []models.Message) bool {
+		for _, msg := range messages {
+			if _, ok := keys[Key(msg)]; ok {
+				caughtUp = true
+				continue
+			}
+			if opts.Source != "" {
+				msg.Source = opts.Source
+			}
+			newMessages = append(newMessages, msg)
+		}
+		// messages are ordered newest to oldest, so all following messages are archived already
+		return !stopEarly || !caughtUp
+	})
+	if err != nil {
+		return 0, archived, err
+	}
+
+	added, err := a.Append(newMessages, keys)
+	if err != nil {
+		return added, archived + added, err
+	}
+
+	switch {
+	case from.IsZero():
+		// the history was fetched down to its oldest message or to the archived ones of a complete archive
+		err = a.SetPartial(false)
+	case !caughtUp && added > 0:
+		// the messages before the range start may be missing
+		err = a.SetPartial(true)
+	}
+	return added, archived + added, err
+}
+
+// Partial reports whether older messages than the archived ones may be missing, because a sync with a range start
+// didn't reach the archived messages. Syncs only stop at the first archived message if the archive is not partial.
+func (a *Archive) Partial() (bool, error) {
+	_, err := os.Stat(a.partialPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read archive state: %w", err)
+	}
+	return true, nil
+}
+
+// SetPartial records whether older messages than the archived ones may be missing
+func (a *Archive) SetPartial(partial bool) error {
+	if !partial {
+		if err := os.Remove(a.partialPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to write archive state: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(a.partialPath(), nil, 0o600); err != nil {
+		return fmt.Errorf("failed to write archive state: %w", err)
+	}
+	return nil
+}
+
+// partialPath is the marker file of a partial archive
+func (a *Archive) partialPath() string {
+	return a.path + ".partial"
+}
+
+// FetchAllMessages implements source.Source
+func (a *Archive) FetchAllMessages(ctx context.Context, from, until time.Time, cb func(messages []models.Message) bool) error {
+	if _, err := os.Stat(a.path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("archive %s does not exist, run the sync command first", a.path)
+	}
+	return source.NewFile(a.path).FetchAllMessages(ctx, from, until, cb)
+}
+
+// readAll reads all archived messages; a missing archive is empty
+func (a *Archive) readAll() ([]models.Message, error) {
+	f, err := os.Open(a.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	messages, err := source.ReadMessages(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", a.path, err)
+	}
+	return messages, nil
+}
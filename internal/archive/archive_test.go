@@ -0,0 +1,195 @@
+package archive
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+var start = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// history returns n hourly messages, newest first
+func history(n int) []models.Message {
+	messages := make([]models.Message, n)
+	for i := range n {
+		messages[n-1-i] = models.Message{
+			Marker:     strconv.Itoa(i + 1),
+			MessageTag: 100 + i,
+			MessageID:  models.MessageIDChargingStarted,
+			Timestamp:  start.Add(time.Duration(i) * time.Hour),
+		}
+	}
+	return messages
+}
+
+// pagedSource serves its messages newest first in pages of two, like the device
+type pagedSource struct {
+	messages []models.Message
+	pages    int
+}
+
+func (s *pagedSource) FetchAllMessages(_ context.Context, from, until time.Time, cb func(messages []models.Message) bool) error {
+	var inRange []models.Message
+	for _, msg := range s.messages {
+		if !msg.Timestamp.Before(from) && msg.Timestamp.Before(until) {
+			inRange = append(inRange, msg)
+		}
+	}
+	for i := 0; i < len(inRange); i += 2 {
+		s.pages++
+		if !cb(inRange[i:min(i+2, len(inRange))]) {
+			break
+		}
+	}
+	return nil
+}
+
+// markers returns the markers of the archived messages, newest first
+func markers(t *testing.T, a *Archive) []string {
+	t.Helper()
+	var result []string
+	err := a.FetchAllMessages(context.Background(), time.Time{}, models.TimeMax, func(messages []models.Message) bool {
+		for _, msg := range messages {
+			result = append(result, msg.Marker)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("FetchAllMessages() error = %v", err)
+	}
+	return result
+}
+
+// sync synchronizes the archive and checks the number of added messages
+func sync(t *testing.T, a *Archive, src *pagedSource, from time.Time, opts SyncOptions, wantAdded int) {
+	t.Helper()
+	added, total, err := a.Sync(context.Background(), src, from, models.TimeMax, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if added != wantAdded {
+		t.Errorf("Sync() added %d messages, want %d", added, wantAdded)
+	}
+	if archived := len(markers(t, a)); total != archived {
+		t.Errorf("Sync() total = %d, want %d", total, archived)
+	}
+}
+
+func TestAppendSkipsArchivedMessages(t *testing.T) {
+	a := Open(filepath.Join(t.TempDir(), "archive.jsonl"))
+	messages := history(4)
+
+	keys, err := a.Keys()
+	if err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	}
+	if added, err := a.Append(messages[2:], keys); err != nil || added != 2 {
+		t.Fatalf("Append() = %d, %v, want 2 added", added, err)
+	}
+	// repeated and already archived messages are skipped
+	if added, err := a.Append(append(messages, messages[0]), keys); err != nil || added != 2 {
+		t.Fatalf("Append() = %d, %v, want 2 added", added, err)
+	}
+	if len(keys) != 4 {
+		t.Errorf("keys = %v, want the keys of the appended messages", keys)
+	}
+
+	reloaded, err := a.Keys()
+	if err != nil || len(reloaded) != 4 {
+		t.Errorf("Keys() = %v, %v, want 4 keys", reloaded, err)
+	}
+	if got, want := markers(t, a), []string{"4", "3", "2", "1"}; !slices.Equal(got, want) {
+		t.Errorf("archived markers = %v, want %v", got, want)
+	}
+}
+
+func TestSyncStopsAtArchivedMessages(t *testing.T) {
+	a := Open(filepath.Join(t.TempDir(), "archive.jsonl"))
+	src := &pagedSource{messages: history(6)}
+	sync(t, a, src, time.Time{}, SyncOptions{}, 6)
+
+	src = &pagedSource{messages: history(9)}
+	sync(t, a, src, time.Time{}, SyncOptions{}, 3)
+	// the second page contains the newest archived message
+	if src.pages != 2 {
+		t.Errorf("fetched %d pages, want 2", src.pages)
+	}
+
+	src = &pagedSource{messages: history(9)}
+	sync(t, a, src, time.Time{}, SyncOptions{Full: true}, 0)
+	if src.pages != 5 {
+		t.Errorf("full sync fetched %d pages, want 5", src.pages)
+	}
+}
+
+func TestSyncWithRangeStartMarksPartial(t *testing.T) {
+	a := Open(filepath.Join(t.TempDir(), "archive.jsonl"))
+	sync(t, a, &pagedSource{messages: history(4)}, time.Time{}, SyncOptions{}, 4)
+
+	// a sync of the last 3 hours doesn't reach the archived messages
+	sync(t, a, &pagedSource{messages: history(10)}, start.Add(7*time.Hour), SyncOptions{}, 3)
+	if partial, err := a.Partial(); err != nil || !partial {
+		t.Fatalf("Partial() = %v, %v, want true", partial, err)
+	}
+
+	// the gap is fetched, although the newest messages are archived already
+	src := &pagedSource{messages: history(10)}
+	sync(t, a, src, time.Time{}, SyncOptions{}, 3)
+	if src.pages != 5 {
+		t.Errorf("fetched %d pages, want all 5", src.pages)
+	}
+	if partial, err := a.Partial(); err != nil || partial {
+		t.Errorf("Partial() = %v, %v, want false", partial, err)
+	}
+	if got, want := markers(t, a), []string{"10", "9", "8", "7", "6", "5", "4", "3", "2", "1"}; !slices.Equal(got, want) {
+		t.Errorf("archived markers = %v, want %v", got, want)
+	}
+
+	// a range start within the archived messages leaves the archive complete
+	sync(t, a, &pagedSource{messages: history(12)}, start.Add(8*time.Hour), SyncOptions{}, 2)
+	if partial, err := a.Partial(); err != nil || partial {
+		t.Errorf("Partial() = %v, %v, want false", partial, err)
+	}
+}
+
+func TestSyncTagsSource(t *testing.T) {
+	a := Open(filepath.Join(t.TempDir(), "archive.jsonl"))
+	sync(t, a, &pagedSource{messages: history(2)}, time.Time{}, SyncOptions{Source: "garage"}, 2)
+
+	err := a.FetchAllMessages(context.Background(), time.Time{}, models.TimeMax, func(messages []models.Message) bool {
+		for _, msg := range messages {
+			if msg.Source != "garage" {
+				t.Errorf("message %s source = %q, want garage", msg.Marker, msg.Source)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("FetchAllMessages() error = %v", err)
+	}
+}
+
+func TestFetchAllMessagesWithoutArchive(t *testing.T) {
+	a := Open(filepath.Join(t.TempDir(), "missing.jsonl"))
+	err := a.FetchAllMessages(context.Background(), time.Time{}, models.TimeMax, func([]models.Message) bool { return true })
+	if err == nil {
+		t.Error("FetchAllMessages() of a missing archive succeeded, want an error")
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+
+	path, err := DefaultPath("https://device.local:8443")
+	if err != nil {
+		t.Fatalf("DefaultPath() error = %v", err)
+	}
+	if want := filepath.Join("/data", "sma_chg_log", "device.local_8443.jsonl"); path != want {
+		t.Errorf("DefaultPath() = %s, want %s", path, want)
+	}
+}
@@ -0,0 +1,163 @@
+package authmap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// Entry maps an authentication value (e.g. an RFID card) to a user
+type Entry struct {
+	Authentication string
+	User           models.User
+	// ActiveFrom and ActiveUntil limit the entry to sessions started within the dates (both inclusive, optional)
+	ActiveFrom  time.Time
+	ActiveUntil time.Time
+	// line is the line of the entry in its source (0 for command line entries)
+	line int
+}
+
+// active reports whether the entry applies to a session started at t
+func (e Entry) active(t time.Time) bool {
+	if !e.ActiveFrom.IsZero() && t.Before(e.ActiveFrom) {
+		return false
+	}
+	if !e.ActiveUntil.IsZero() && !t.Before(e.ActiveUntil.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// overlaps reports whether the active ranges of both entries overlap
+func (e Entry) overlaps(o Entry) bool {
+	startsBeforeEnd := func(a, b Entry) bool {
+		return a.ActiveFrom.IsZero() || b.ActiveUntil.IsZero() || !a.ActiveFrom.After(b.ActiveUntil)
+	}
+	return startsBeforeEnd(e, o) && startsBeforeEnd(o, e)
+}
+
+// Mapping maps authentication values to users
+type Mapping struct {
+	entries []Entry
+}
+
+// New creates a mapping from the entries. Entries are matched in order, so earlier entries take precedence.
+func New(entries ...Entry) *Mapping {
+	return &Mapping{entries: entries}
+}
+
+// Load loads a mapping file in YAML (.yaml/.yml) or CSV (.csv) format.
+// Times of the active date ranges are interpreted in loc.
+func Load(path string, loc *time.Location) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authentication mapping: %w", err)
+	}
+
+	var entries []Entry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		entries, err = parseYAML(data, loc)
+	case ".csv":
+		entries, err = parseCSV(data, loc)
+	default:
+		return nil, fmt.Errorf("authentication mapping must be a .yaml or .csv file: %s", path)
+	}
+	if err == nil {
+		err = validate(entries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid authentication mapping %s:\n%w", path, err)
+	}
+
+	return New(entries...), nil
+}
+
+// ParseFlags parses mapping entries in the format old:new
+func ParseFlags(raw []string) ([]Entry, error) {
+	var entries []Entry
+	var errs []error
+	for _, value := range raw {
+		old, name, ok := strings.Cut(value, ":")
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid authentication mapping %q (format: old:new)", value))
+			continue
+		}
+		entries = append(entries, Entry{Authentication: old, User: models.User{Name: name}})
+	}
+	return entries, errors.Join(errs...)
+}
+
+// Entries returns the entries of the mapping
+func (m *Mapping) Entries() []Entry {
+	return m.entries
+}
+
+// Lookup finds the entry for an authentication value of a session started at t
+func (m *Mapping) Lookup(authentication string, t time.Time) (Entry, bool) {
+	for _, e := range m.entries {
+		if e.Authentication == authentication && e.active(t) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Apply replaces the authentication of the sessions by the mapped display name
+// and attaches the user metadata
+func (m *Mapping) Apply(sessions []models.ChargingSession) {
+	for i := range sessions {
+		at := sessions[i].Start
+		if at.IsZero() {
+			at = sessions[i].End
+		}
+
+		e, ok := m.Lookup(sessions[i].Authentication, at)
+		if !ok {
+			continue
+		}
+		if e.User.Name != "" {
+			sessions[i].Authentication = e.User.Name
+		}
+		if e.User.HasMetadata() {
+			user := e.User
+			sessions[i].User = &user
+		}
+	}
+}
+
+// validate checks for conflicting entries, i.e. the same authentication with overlapping active ranges
+func validate(entries []Entry) error {
+	var errs []error
+	for i, e := range entries {
+		if !e.ActiveFrom.IsZero() && !e.ActiveUntil.IsZero() && e.ActiveUntil.Before(e.ActiveFrom) {
+			errs = append(errs, fmt.Errorf("line %d: active-until is before active-from", e.line))
+		}
+		for _, prev := range entries[:i] {
+			if prev.Authentication == e.Authentication && prev.overlaps(e) {
+				errs = append(errs, fmt.Errorf("line %d: authentication %q overlaps with entry in line %d", e.line, e.Authentication, prev.line))
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseDate parses an optional date of an active range
+func parseDate(field, value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be in format YYYY-MM-DD: %q", field, value)
+	}
+	return t, nil
+}
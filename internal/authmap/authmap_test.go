@@ -0,0 +1,152 @@
+package authmap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// writeFile writes a mapping file to a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		entries int
+		wantErr string
+	}{
+		{name: "yaml", file: "map.yaml", content: `
+- authentication: card-a
+  name: Alice
+  cost-center: "4711"
+- authentication: card-b
+  name: Bob
+`, entries: 2},
+		{name: "csv", file: "map.csv", content: "Authentication,Name,Employee Number\ncard-a,Alice,17\ncard-b,Bob,18\n", entries: 2},
+		{name: "empty csv", file: "map.csv", content: ""},
+		{name: "yaml without authentication", file: "map.yml", content: "- name: Alice\n", wantErr: "line 1: authentication is required"},
+		{name: "yaml with unknown field", file: "map.yaml", content: "- authentication: card-a\n  phone: 123\n", wantErr: `line 1: unknown field "phone"`},
+		{name: "yaml without list", file: "map.yaml", content: "authentication: card-a\n", wantErr: "expected a list of entries"},
+		{name: "csv with unknown column", file: "map.csv", content: "authentication,phone\n", wantErr: `line 1: unknown column "phone"`},
+		{name: "csv without authentication", file: "map.csv", content: "name\nAlice\n", wantErr: "column authentication is required"},
+		{name: "invalid date", file: "map.csv", content: "authentication,active-from\ncard-a,01.01.2026\n", wantErr: "line 2: active-from must be in format YYYY-MM-DD"},
+		{name: "unknown extension", file: "map.json", content: "[]", wantErr: "must be a .yaml or .csv file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := Load(writeFile(t, tt.file, tt.content), time.UTC)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(mapping.Entries()) != tt.entries {
+				t.Errorf("Load() = %d entries, want %d", len(mapping.Entries()), tt.entries)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsOverlaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    string
+		wantErr string
+	}{
+		{name: "consecutive ranges", rows: "card-a,Alice,,2026-03-31\ncard-a,Bob,2026-04-01,\n"},
+		{name: "other authentication", rows: "card-a,Alice,,\ncard-b,Bob,,\n"},
+		{name: "unlimited entries", rows: "card-a,Alice,,\ncard-a,Bob,,\n", wantErr: `line 3: authentication "card-a" overlaps with entry in line 2`},
+		{name: "open end", rows: "card-a,Alice,2026-01-01,\ncard-a,Bob,2026-06-01,2026-06-30\n", wantErr: "line 3"},
+		{name: "open start", rows: "card-a,Alice,,2026-03-31\ncard-a,Bob,2026-01-01,2026-01-31\n", wantErr: "line 3"},
+		// the until date is inclusive
+		{name: "same day", rows: "card-a,Alice,,2026-03-31\ncard-a,Bob,2026-03-31,\n", wantErr: "line 3"},
+		{name: "reversed range", rows: "card-a,Alice,2026-03-31,2026-03-01\n", wantErr: "line 2: active-until is before active-from"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "map.csv", "authentication,name,active-from,active-until\n"+tt.rows)
+			_, err := Load(path, time.UTC)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	mapping, err := Load(writeFile(t, "map.yaml", `
+- authentication: card-a
+  name: Alice
+  active-until: 2026-03-31
+- authentication: card-a
+  name: Bob
+  vehicle: Model Y
+  active-from: 2026-04-01
+- authentication: ""
+  name: Unknown
+`), time.UTC)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	sessions := []models.ChargingSession{
+		{Authentication: "card-a", Start: day(3, 31)},
+		{Authentication: "card-a", Start: day(4, 1)},
+		// a session without start is mapped by its end
+		{Authentication: "card-a", End: day(4, 2)},
+		{Authentication: ""},
+		{Authentication: "card-c", Start: day(4, 1)},
+	}
+	mapping.Apply(sessions)
+
+	for i, want := range []string{"Alice", "Bob", "Bob", "Unknown", "card-c"} {
+		if sessions[i].Authentication != want {
+			t.Errorf("session %d authentication = %q, want %q", i, sessions[i].Authentication, want)
+		}
+	}
+	if sessions[0].User != nil {
+		t.Errorf("session 0 user = %+v, want no metadata", sessions[0].User)
+	}
+	if sessions[1].User == nil || sessions[1].User.Vehicle != "Model Y" {
+		t.Errorf("session 1 user = %+v, want the vehicle", sessions[1].User)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	entries, err := ParseFlags([]string{"old:new", ":Unknown User"})
+	if err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Authentication != "old" || entries[1].User.Name != "Unknown User" {
+		t.Errorf("ParseFlags() = %+v", entries)
+	}
+	if _, err := ParseFlags([]string{"invalid"}); err == nil {
+		t.Error("ParseFlags() without a colon succeeded, want an error")
+	}
+}
@@ -0,0 +1,96 @@
+package authmap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// csvColumns maps the normalized column names to a setter on the entry
+var csvColumns = map[string]func(e *Entry, value string){
+	"authentication":  func(e *Entry, v string) { e.Authentication = v },
+	"name":            func(e *Entry, v string) { e.User.Name = v },
+	"employee-number": func(e *Entry, v string) { e.User.EmployeeNumber = v },
+	"cost-center":     func(e *Entry, v string) { e.User.CostCenter = v },
+	"vehicle":         func(e *Entry, v string) { e.User.Vehicle = v },
+	"license-plate":   func(e *Entry, v string) { e.User.LicensePlate = v },
+}
+
+// parseCSV parses entries from CSV with a header row naming the columns
+func parseCSV(data []byte, loc *time.Location) ([]Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(header))
+	hasAuthentication := false
+	for i, name := range header {
+		column := normalizeColumn(name)
+		switch column {
+		case "authentication":
+			hasAuthentication = true
+		case "active-from", "active-until":
+		default:
+			if _, ok := csvColumns[column]; !ok {
+				return nil, fmt.Errorf("line 1: unknown column %q", name)
+			}
+		}
+		columns[i] = column
+	}
+	if !hasAuthentication {
+		return nil, errors.New("line 1: column authentication is required")
+	}
+
+	var entries []Entry
+	var errs []error
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		entry := Entry{line: line}
+		var entryErr error
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch columns[i] {
+			case "active-from":
+				entry.ActiveFrom, entryErr = parseDate(columns[i], value, loc)
+			case "active-until":
+				entry.ActiveUntil, entryErr = parseDate(columns[i], value, loc)
+			default:
+				csvColumns[columns[i]](&entry, value)
+			}
+			if entryErr != nil {
+				break
+			}
+		}
+		if entryErr != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, entryErr))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errors.Join(errs...)
+}
+
+// normalizeColumn normalizes a column name, e.g. "Employee Number" to "employee-number"
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "-", "_", "-").Replace(name)
+}
@@ -0,0 +1,93 @@
+package authmap
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// yamlEntry is the YAML representation of an entry
+type yamlEntry struct {
+	Authentication *string `yaml:"authentication"`
+	Name           string  `yaml:"name"`
+	EmployeeNumber string  `yaml:"employee-number"`
+	CostCenter     string  `yaml:"cost-center"`
+	Vehicle        string  `yaml:"vehicle"`
+	LicensePlate   string  `yaml:"license-plate"`
+	ActiveFrom     string  `yaml:"active-from"`
+	ActiveUntil    string  `yaml:"active-until"`
+}
+
+// parseYAML parses a list of entries, reporting errors with line numbers
+func parseYAML(data []byte, loc *time.Location) ([]Entry, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	list := doc.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: expected a list of entries", list.Line)
+	}
+
+	var entries []Entry
+	var errs []error
+	for _, node := range list.Content {
+		entry, err := parseYAMLEntry(node, loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", node.Line, err))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errors.Join(errs...)
+}
+
+func parseYAMLEntry(node *yaml.Node, loc *time.Location) (Entry, error) {
+	if node.Kind != yaml.MappingNode {
+		return Entry{}, errors.New("expected an entry with authentication and user fields")
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		switch key := node.Content[i].Value; key {
+		case "authentication", "name", "employee-number", "cost-center", "vehicle", "license-plate", "active-from", "active-until":
+		default:
+			return Entry{}, fmt.Errorf("unknown field %q", key)
+		}
+	}
+
+	var raw yamlEntry
+	if err := node.Decode(&raw); err != nil {
+		return Entry{}, err
+	}
+	if raw.Authentication == nil {
+		return Entry{}, errors.New("authentication is required")
+	}
+
+	entry := Entry{
+		Authentication: *raw.Authentication,
+		User: models.User{
+			Name:           raw.Name,
+			EmployeeNumber: raw.EmployeeNumber,
+			CostCenter:     raw.CostCenter,
+			Vehicle:        raw.Vehicle,
+			LicensePlate:   raw.LicensePlate,
+		},
+		line: node.Line,
+	}
+
+	var err error
+	if entry.ActiveFrom, err = parseDate("active-from", raw.ActiveFrom, loc); err != nil {
+		return Entry{}, err
+	}
+	if entry.ActiveUntil, err = parseDate("active-until", raw.ActiveUntil, loc); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
@@ -1,29 +1,96 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/joshiste/sma_chg_log/internal/models"
 )
 
+const (
+	tokenPath        = "/api/v1/token"
+	refreshTokenPath = "/api/v1/refreshtoken"
+)
+
+// tokenExpiryMargin renews tokens shortly before they expire, so requests in flight don't fail
+const tokenExpiryMargin = 30 * time.Second
+
+// token is an access token with its optional refresh token
+type token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+}
+
+// valid reports whether the access token can be used (tokens without expiry are used until rejected)
+func (t token) valid(now time.Time) bool {
+	return t.AccessToken != "" && (t.ExpiresAt.IsZero() || now.Add(tokenExpiryMargin).Before(t.ExpiresAt))
+}
+
+// getToken returns a valid access token: the current or cached one, a refreshed one or a new one
+func (c *Client) getToken(ctx context.Context) (string, error) {
+	c.loadCachedToken()
+
+	if c.token.valid(time.Now()) {
+		return c.token.AccessToken, nil
+	}
+
+	if c.token.RefreshToken != "" {
+		err := c.refreshToken(ctx)
+		if err == nil {
+			return c.token.AccessToken, nil
+		}
+		slog.Debug("token refresh failed, logging in again", "error", err)
+	}
+
+	if err := c.fetchToken(ctx); err != nil {
+		return "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+	return c.token.AccessToken, nil
+}
+
+// invalidateToken discards the access token rejected by the device, the refresh token is kept
+func (c *Client) invalidateToken() {
+	c.token.AccessToken = ""
+	c.token.ExpiresAt = time.Time{}
+}
+
 // fetchToken retrieves a new bearer token from the token endpoint
-func (c *Client) fetchToken() error {
+func (c *Client) fetchToken(ctx context.Context) error {
 	slog.Debug("fetching new token")
 
-	tokenURL := c.baseURL + "/api/v1/token"
-
 	data := url.Values{}
 	data.Set("grant_type", "password")
 	data.Set("username", c.username)
 	data.Set("password", c.password)
 
-	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
+	return c.requestToken(ctx, data)
+}
+
+// refreshToken retrieves a new bearer token with the refresh token
+func (c *Client) refreshToken(ctx context.Context) error {
+	slog.Debug("refreshing token")
+
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", c.token.RefreshToken)
+
+	return c.requestToken(ctx, data)
+}
+
+// requestToken posts the grant to the token endpoint and stores the received token
+func (c *Client) requestToken(ctx context.Context, data url.Values) error {
+	tokenURL := c.baseURL + tokenPath
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create token request: %w", err)
 	}
@@ -32,14 +99,14 @@ func (c *Client) fetchToken() error {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch token: %w", err)
+		return fmt.Errorf("failed to fetch token: %w", wrapTLSError(err))
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("token request failed with status: %d", resp.StatusCode)
+		return newStatusError("token request failed", resp)
 	}
 
 	var tokenResp models.TokenResponse
@@ -51,6 +118,89 @@ func (c *Client) fetchToken() error {
 		return fmt.Errorf("received empty access token")
 	}
 
-	c.token = tokenResp.AccessToken
+	refreshToken := tokenResp.RefreshToken
+	if refreshToken == "" {
+		// devices may only return the refresh token on login
+		refreshToken = c.token.RefreshToken
+	}
+	c.token = token{AccessToken: tokenResp.AccessToken, RefreshToken: refreshToken}
+	if tokenResp.ExpiresIn > 0 {
+		c.token.ExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	slog.Debug("received token", "expires", c.token.ExpiresAt, "refreshable", c.token.RefreshToken != "")
+
+	if c.tokenCache != nil {
+		if err := c.tokenCache.save(c.token); err != nil {
+			slog.Warn("failed to cache token", "path", c.tokenCache.Path(), "error", err)
+		}
+	}
+	return nil
+}
+
+// loadCachedToken initializes the token from the cache once
+func (c *Client) loadCachedToken() {
+	if c.tokenCache == nil || c.tokenLoaded {
+		return
+	}
+	c.tokenLoaded = true
+
+	cached, err := c.tokenCache.load()
+	if err != nil {
+		slog.Warn("ignoring token cache", "path", c.tokenCache.Path(), "error", err)
+		return
+	}
+	if cached.AccessToken != "" {
+		slog.Debug("using cached token", "path", c.tokenCache.Path(), "expires", cached.ExpiresAt)
+		c.token = cached
+	}
+}
+
+// Login fetches a new token, verifying the credentials
+func (c *Client) Login(ctx context.Context) error {
+	return c.retry(ctx, func() error {
+		return c.fetchToken(ctx)
+	})
+}
+
+// Logout revokes the refresh token on the device and deletes the cached token
+func (c *Client) Logout(ctx context.Context) error {
+	c.loadCachedToken()
+
+	var err error
+	if c.token.RefreshToken != "" {
+		err = c.revokeToken(ctx)
+	}
+	c.token = token{}
+
+	if c.tokenCache != nil {
+		err = errors.Join(err, c.tokenCache.Delete())
+	}
+	return err
+}
+
+// revokeToken deletes the refresh token on the device
+func (c *Client) revokeToken(ctx context.Context) error {
+	slog.Debug("revoking token")
+
+	revokeURL := c.baseURL + refreshTokenPath + "?" + url.Values{"refreshToken": {c.token.RefreshToken}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, revokeURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create revoke request: %w", err)
+	}
+	if c.token.valid(time.Now()) {
+		req.Header.Set("Authorization", "Bearer "+c.token.AccessToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", wrapTLSError(err))
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newStatusError("revoke request failed", resp)
+	}
 	return nil
 }
@@ -0,0 +1,287 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// interaction is a recorded request/response pair, stored as one JSON file of a cassette directory
+type interaction struct {
+	Request  recordedRequest   `json:"request"`
+	Response *recordedResponse `json:"response,omitempty"`
+	// Error is the transport error of a request that got no response
+	Error string `json:"error,omitempty"`
+}
+
+type recordedRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// recordingTransport wraps an http.RoundTripper and writes each request/response pair
+// with credentials redacted to a cassette directory
+type recordingTransport struct {
+	transport http.RoundTripper
+	redactor  redactor
+	dir       string
+
+	mu    sync.Mutex
+	count int
+}
+
+// newRecordingTransport creates a transport recording to dir, redacting the given body fields
+func newRecordingTransport(transport http.RoundTripper, dir string, redactFields []string) *recordingTransport {
+	return &recordingTransport{
+		transport: transport,
+		redactor:  newRedactor(redactFields),
+		dir:       dir,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	encodedReqBody, err := t.encodeBody(req.Header.Get("Content-Type"), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	recorded := interaction{
+		Request: recordedRequest{
+			Method:  req.Method,
+			Path:    req.URL.Path,
+			Headers: t.redactor.headers(req.Header),
+			Body:    encodedReqBody,
+		},
+	}
+	if req.URL.RawQuery != "" {
+		recorded.Request.Query = t.redactor.values(req.URL.Query())
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		recorded.Error = err.Error()
+		if writeErr := t.write(recorded); writeErr != nil {
+			return nil, errors.Join(err, writeErr)
+		}
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	encodedRespBody, err := t.encodeBody(resp.Header.Get("Content-Type"), respBody)
+	if err != nil {
+		return nil, err
+	}
+	recorded.Response = &recordedResponse{
+		Status:  resp.StatusCode,
+		Headers: t.redactor.headers(resp.Header),
+		Body:    encodedRespBody,
+	}
+	// the length of a redacted body differs
+	delete(recorded.Response.Headers, "Content-Length")
+	if err := t.write(recorded); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// encodeBody redacts the body and embeds it as JSON if possible, as a JSON string otherwise
+func (t *recordingTransport) encodeBody(contentType string, body []byte) (json.RawMessage, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	body = t.redactor.body(contentType, body)
+	if isJSON(contentType) && json.Valid(body) {
+		return body, nil
+	}
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(string(body)); err != nil {
+		return nil, fmt.Errorf("failed to encode recorded body: %w", err)
+	}
+	return bytes.TrimSuffix(encoded.Bytes(), []byte("\n")), nil
+}
+
+// write stores the interaction as the next numbered file of the cassette
+func (t *recordingTransport) write(recorded interaction) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(recorded); err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+	if t.count == 0 {
+		// the interactions of another recording would be replayed interleaved with these
+		if err := ValidateRecordDir(t.dir); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	t.count++
+	name := fmt.Sprintf("%04d-%s%s.json", t.count, strings.ToLower(recorded.Request.Method),
+		strings.ReplaceAll(recorded.Request.Path, "/", "_"))
+	if err := os.WriteFile(filepath.Join(t.dir, name), data.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// replayingTransport serves the responses of a cassette directory instead of contacting the device.
+// Requests are answered with the recorded interactions of the same method and path in recording order.
+type replayingTransport struct {
+	dir string
+
+	once         sync.Once
+	err          error
+	mu           sync.Mutex
+	interactions map[string][]interaction
+}
+
+// newReplayingTransport creates a transport replaying the cassette in dir (loaded on the first request)
+func newReplayingTransport(dir string) *replayingTransport {
+	return &replayingTransport{dir: dir}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	t.once.Do(func() {
+		t.interactions, t.err = loadCassette(t.dir)
+	})
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	key := req.Method + " " + req.URL.Path
+	t.mu.Lock()
+	queue := t.interactions[key]
+	if len(queue) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("no recorded response left for %s in %s", key, t.dir)
+	}
+	recorded := queue[0]
+	t.interactions[key] = queue[1:]
+	t.mu.Unlock()
+
+	if recorded.Response == nil {
+		return nil, fmt.Errorf("recorded error: %s", recorded.Error)
+	}
+
+	header := make(http.Header, len(recorded.Response.Headers))
+	for key, value := range recorded.Response.Headers {
+		header.Set(key, value)
+	}
+	body, err := decodeBody(recorded.Response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recorded response body for %s: %w", key, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Response.Status, http.StatusText(recorded.Response.Status)),
+		StatusCode:    recorded.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// ValidateRecordDir checks that the recording directory holds no previous recording
+func ValidateRecordDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("recording directory %s is not empty", dir)
+	}
+	return nil
+}
+
+// loadCassette reads the interactions of a cassette directory grouped by method and path
+func loadCassette(dir string) (map[string][]interaction, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recordings found in %s", dir)
+	}
+	slices.Sort(files)
+
+	interactions := make(map[string][]interaction)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var recorded interaction
+		if err := json.Unmarshal(data, &recorded); err != nil {
+			return nil, fmt.Errorf("invalid recording %s: %w", file, err)
+		}
+		key := recorded.Request.Method + " " + recorded.Request.Path
+		interactions[key] = append(interactions[key], recorded)
+	}
+	return interactions, nil
+}
+
+// decodeBody returns the body embedded by encodeBody
+func decodeBody(raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 || raw[0] != '"' {
+		return raw, nil
+	}
+	var body string
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, err
+	}
+	return []byte(body), nil
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/pairing"
+	"github.com/joshiste/sma_chg_log/internal/simulator"
+)
+
+var update = flag.Bool("update", false, "record the cassette of testdata/cassette against the simulator")
+
+const cassetteDir = "testdata/cassette"
+
+// the range of the recorded cassette
+var (
+	cassetteFrom  = testEnd.AddDate(0, 0, -3)
+	cassetteUntil = testEnd
+)
+
+// recordCassette fetches the cassette range from a simulated device, recording to dir. It returns
+// the fetched messages and the access tokens issued by the device.
+func recordCassette(t *testing.T, dir string) ([]models.Message, []string) {
+	t.Helper()
+	device := simulator.NewWithOptions(simulator.Options{
+		Username: "installer",
+		Password: "s3cr3t-pa55",
+		Messages: simulator.Generate(10, testEnd, 4),
+		PageSize: 5,
+		Seed:     10,
+	})
+
+	var mu sync.Mutex
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			mu.Lock()
+			tokens = append(tokens, token)
+			mu.Unlock()
+		}
+		device.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewWithOptions(server.URL, "installer", "s3cr3t-pa55", Options{RecordDir: dir})
+	return fetchAll(t, c, cassetteFrom, cassetteUntil), tokens
+}
+
+func TestReplayCassette(t *testing.T) {
+	if *update {
+		if err := os.RemoveAll(cassetteDir); err != nil {
+			t.Fatal(err)
+		}
+		recordCassette(t, cassetteDir)
+	}
+
+	// the replayed client never contacts the device
+	c := NewWithOptions("http://device.invalid", "installer", "s3cr3t-pa55", Options{ReplayDir: cassetteDir})
+	sessions := pairing.Pair(fetchAll(t, c, cassetteFrom, cassetteUntil))
+
+	type summary struct {
+		charger, authentication string
+		start, end              string
+		consumption             float64
+		status                  models.SessionStatus
+	}
+	var got []summary
+	for _, s := range sessions {
+		got = append(got, summary{s.ChargerName, s.Authentication,
+			s.Start.UTC().Format(time.DateTime), s.End.UTC().Format(time.DateTime), s.Consumption, s.Status})
+	}
+	// the sessions of both chargers overlap on the 27th, the last one was still charging
+	want := []summary{
+		{"EV Charger Garage", "0489ABCDEF0123", "2026-02-28 18:45:57", "0001-01-01 00:00:00", 0, models.StatusIncomplete},
+		{"EV Charger Carport", "04A1B2C3D4E5F6", "2026-02-27 19:49:50", "2026-02-28 00:39:54", 41.48, models.StatusCompleted},
+		{"EV Charger Garage", "Guest", "2026-02-27 20:53:15", "2026-02-27 23:24:29", 11.7, models.StatusCompleted},
+		{"EV Charger Garage", "0489ABCDEF0123", "2026-02-26 18:10:59", "2026-02-26 22:11:59", 23.57, models.StatusCompleted},
+		{"EV Charger Carport", "04F6E5D4C3B2A1", "2026-02-26 18:14:00", "2026-02-26 22:02:47", 40.86, models.StatusCompleted},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paired sessions = %#v, want %#v", got, want)
+	}
+}
+
+func TestRecordingIsReplayable(t *testing.T) {
+	dir := t.TempDir()
+	live, tokens := recordCassette(t, dir)
+	if len(tokens) == 0 {
+		t.Fatal("no access token used")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 3 {
+		t.Fatalf("%d recorded interactions, want the token request and several pages", len(files))
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range append([]string{"s3cr3t-pa55"}, tokens...) {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains the credential %q", filepath.Base(file), secret)
+			}
+		}
+	}
+
+	c := NewWithOptions("http://device.invalid", "installer", "s3cr3t-pa55", Options{ReplayDir: dir})
+	replayed := fetchAll(t, c, cassetteFrom, cassetteUntil)
+
+	if got, want := markers(replayed, cassetteFrom, cassetteUntil), markers(live, cassetteFrom, cassetteUntil); len(want) == 0 || !slices.Equal(got, want) {
+		t.Errorf("replayed markers = %v, want %v", got, want)
+	}
+}
+
+func TestRecordingRefusesPreviousRecording(t *testing.T) {
+	dir := t.TempDir()
+	recordCassette(t, dir)
+
+	_, server := newTestDevice(t, simulator.Options{Seed: 10})
+	err := NewWithOptions(server.URL, "user", "password", Options{RecordDir: dir}).FetchAllMessages(context.Background(),
+		cassetteFrom, cassetteUntil, func([]models.Message) bool { return true })
+	if err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("FetchAllMessages() error = %v, want the recording directory to be refused", err)
+	}
+}
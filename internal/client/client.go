@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -20,55 +21,120 @@ const (
 
 // Client handles HTTP communication with the SMA API
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	username   string
-	password   string
-	token      string
+	httpClient  *http.Client
+	baseURL     string
+	retryPolicy RetryPolicy
+	username    string
+	password    string
+	token       token
+	tokenCache  *TokenCache
+	tokenLoaded bool
+
+	serverFilter     filterSupport
+	messageGroupTags []int
+}
+
+// Options contains options for the client
+type Options struct {
+	// TLS is the TLS configuration for the connection to the device (see NewTLSConfig)
+	TLS *tls.Config
+	// RedactFields are the body fields redacted from trace logs (DefaultRedactFields if nil)
+	RedactFields []string
+	// Timeout limits each request to the device (no limit if zero)
+	Timeout time.Duration
+	// Retry is the policy for retrying transient errors (DefaultRetryPolicy if MaxAttempts is zero)
+	Retry RetryPolicy
+	// DisableServerFilter always fetches the complete history and filters messages locally
+	DisableServerFilter bool
+	// MessageGroupTags restricts the fetched messages to these message groups (all if empty)
+	MessageGroupTags []int
+	// RecordDir is an empty or new directory the requests and responses are recorded to (credentials redacted)
+	RecordDir string
+	// ReplayDir is a directory of recorded responses served instead of contacting the device
+	ReplayDir string
+	// TokenCache keeps the token between runs, so not every run logs in (no caching if nil)
+	TokenCache *TokenCache
 }
 
 // New creates a new Client instance
 func New(url, username, password string) *Client {
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
+	return NewWithOptions(url, username, password, Options{})
+}
+
+// NewWithOptions creates a new Client instance with options
+func NewWithOptions(url, username, password string, opts Options) *Client {
+	redactFields := opts.RedactFields
+	if redactFields == nil {
+		redactFields = DefaultRedactFields
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: opts.TLS,
+	}
+	if opts.ReplayDir != "" {
+		transport = newReplayingTransport(opts.ReplayDir)
+	} else if opts.RecordDir != "" {
+		transport = newRecordingTransport(transport, opts.RecordDir, redactFields)
+	}
+
+	retryPolicy := opts.Retry
+	if retryPolicy.MaxAttempts == 0 {
+		retryPolicy = DefaultRetryPolicy
+	}
+
+	serverFilter := filterUnknown
+	if opts.DisableServerFilter {
+		serverFilter = filterUnsupported
 	}
 
 	slog.Debug("initializing client", "url", url, "username", username)
 
 	return &Client{
 		httpClient: &http.Client{
-			Transport: newLoggingTransport(transport),
+			Transport: newLoggingTransport(transport, redactFields),
+			Timeout:   opts.Timeout,
 		},
-		baseURL:  url,
-		username: username,
-		password: password,
+		baseURL:     url,
+		retryPolicy: retryPolicy,
+		username:    username,
+		password:    password,
+		tokenCache:  opts.TokenCache,
+
+		serverFilter:     serverFilter,
+		messageGroupTags: opts.MessageGroupTags,
 	}
 }
 
-// SearchMessages fetches messages from the API starting at the given marker
-func (c *Client) SearchMessages(marker string, offset int) ([]models.Message, error) {
-	return c.searchMessagesWithRetry(marker, offset, true)
+// SearchMessages fetches messages from the API starting at the given marker.
+// Transient errors are retried according to the retry policy.
+func (c *Client) SearchMessages(ctx context.Context, marker string, offset int) ([]models.Message, error) {
+	return c.search(ctx, searchFilter{}, marker, offset)
 }
 
-func (c *Client) getToken() (string, error) {
-	if c.token == "" {
-		if err := c.fetchToken(); err != nil {
-			return "", fmt.Errorf("failed to refresh token: %w", err)
-		}
-	}
-	return c.token, nil
+// search fetches messages matching the filter, retrying transient errors
+func (c *Client) search(ctx context.Context, filter searchFilter, marker string, offset int) ([]models.Message, error) {
+	var messages []models.Message
+	err := c.retry(ctx, func() error {
+		var err error
+		messages, err = c.searchMessagesWithRetry(ctx, filter, marker, offset, true)
+		return err
+	})
+	return messages, err
 }
 
-func (c *Client) searchMessagesWithRetry(marker string, offset int, retry bool) ([]models.Message, error) {
+func (c *Client) searchMessagesWithRetry(ctx context.Context, filter searchFilter, marker string, offset int, retry bool) ([]models.Message, error) {
 	searchURL := c.baseURL + searchPath
 
+	groupTags := filter.groupTags
+	if groupTags == nil {
+		groupTags = []int{}
+	}
+
 	reqBody := models.SearchRequest{
 		ComponentID:      componentID,
-		From:             nil,
-		Until:            nil,
-		MessageGroupTags: []int{},
+		From:             filter.from,
+		Until:            filter.until,
+		MessageGroupTags: groupTags,
 		TraceLevels:      []string{},
 		Marker:           marker,
 		Offset:           offset,
@@ -79,14 +145,14 @@ func (c *Client) searchMessagesWithRetry(marker string, offset int, retry bool)
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, searchURL, bytes.NewReader(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	if token, err := c.getToken(); err == nil {
+	if token, err := c.getToken(ctx); err == nil {
 		req.Header.Set("Authorization", "Bearer "+token)
 	} else {
 		return nil, err
@@ -94,19 +160,19 @@ func (c *Client) searchMessagesWithRetry(marker string, offset int, retry bool)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, fmt.Errorf("failed to execute request: %w", wrapTLSError(err))
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
 
 	if resp.StatusCode == http.StatusUnauthorized && retry {
-		c.token = ""
-		return c.searchMessagesWithRetry(marker, offset, false)
+		c.invalidateToken()
+		return c.searchMessagesWithRetry(ctx, filter, marker, offset, false)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status: %d", resp.StatusCode)
+		return nil, newStatusError("request failed", resp)
 	}
 
 	var messages []models.Message
@@ -121,12 +187,26 @@ func (c *Client) searchMessagesWithRetry(marker string, offset int, retry bool)
 // FetchAllMessages fetches all messages within the time range, calling the callback for each batch.
 // Messages are returned newest to oldest. Stops fetching when messages are before the from time.
 // The callback returns true to continue fetching, false to stop.
-func (c *Client) FetchAllMessages(from, until time.Time, cb func(messages []models.Message) bool) error {
+//
+// The time range and message groups are sent to the device so it only returns the matching pages.
+// Firmware ignoring or rejecting these parameters is detected on the first page, the messages are
+// then filtered locally while paging back to the from time.
+func (c *Client) FetchAllMessages(ctx context.Context, from, until time.Time, cb func(messages []models.Message) bool) error {
 	marker := ""
 	offset := 0
 
 	for {
-		messages, err := c.SearchMessages(marker, offset)
+		var filter searchFilter
+		if c.serverFilter != filterUnsupported {
+			filter = newSearchFilter(from, until, c.messageGroupTags)
+		}
+
+		messages, err := c.search(ctx, filter, marker, offset)
+		if err != nil && !filter.empty() && c.serverFilter == filterUnknown && filterRejected(err) {
+			slog.Debug("device rejected the search filter, filtering locally", "error", err)
+			c.serverFilter = filterUnsupported
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("failed to fetch messages: %w", err)
 		}
@@ -135,10 +215,14 @@ func (c *Client) FetchAllMessages(from, until time.Time, cb func(messages []mode
 			break
 		}
 
+		if !filter.empty() {
+			c.probeFilter(messages, from, until)
+		}
+
 		// Filter messages within the time range
 		filtered := make([]models.Message, 0, len(messages))
 		for _, msg := range messages {
-			if !msg.Timestamp.Before(from) && msg.Timestamp.Before(until) {
+			if matches(msg, from, until, c.messageGroupTags) {
 				filtered = append(filtered, msg)
 			}
 		}
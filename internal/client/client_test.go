@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/simulator"
+)
+
+// testEnd is the end of the generated message history, fixed so the tests are reproducible
+var testEnd = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestDevice starts a simulated device serving 14 days of messages before testEnd
+func newTestDevice(t *testing.T, opts simulator.Options) (*simulator.Server, *httptest.Server) {
+	t.Helper()
+	if opts.Messages == nil {
+		opts.Messages = simulator.Generate(opts.Seed, testEnd, 14)
+	}
+	device := simulator.NewWithOptions(opts)
+	server := httptest.NewServer(device)
+	t.Cleanup(server.Close)
+	return device, server
+}
+
+// newTestClient creates a client for the simulated device retrying without delay
+func newTestClient(url string) *Client {
+	return NewWithOptions(url, "user", "password", Options{
+		Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+}
+
+// fetchAll fetches the messages of the range with the client
+func fetchAll(t *testing.T, c *Client, from, until time.Time) []models.Message {
+	t.Helper()
+	var fetched []models.Message
+	err := c.FetchAllMessages(context.Background(), from, until, func(messages []models.Message) bool {
+		fetched = append(fetched, messages...)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("FetchAllMessages() error = %v", err)
+	}
+	return fetched
+}
+
+// markers returns the markers of the messages within [from, until)
+func markers(messages []models.Message, from, until time.Time) []string {
+	var result []string
+	for _, msg := range messages {
+		if !msg.Timestamp.Before(from) && msg.Timestamp.Before(until) {
+			result = append(result, msg.Marker)
+		}
+	}
+	return result
+}
+
+func TestFetchAllMessagesPaging(t *testing.T) {
+	from, until := testEnd.AddDate(0, 0, -7), testEnd.AddDate(0, 0, -2)
+
+	for _, ignoreFilter := range []bool{false, true} {
+		device, server := newTestDevice(t, simulator.Options{Seed: 1, PageSize: 7, IgnoreFilter: ignoreFilter})
+
+		fetched := fetchAll(t, newTestClient(server.URL), from, until)
+
+		want := markers(device.Messages(), from, until)
+		if len(want) <= 7 {
+			t.Fatalf("%d messages in range, want several pages", len(want))
+		}
+		if got := markers(fetched, from, until); !slices.Equal(got, want) {
+			t.Errorf("ignoreFilter=%v: fetched markers = %v, want %v", ignoreFilter, got, want)
+		}
+		if len(fetched) != len(want) {
+			t.Errorf("ignoreFilter=%v: fetched %d messages outside of the range", ignoreFilter, len(fetched)-len(want))
+		}
+		if ignoreFilter {
+			continue
+		}
+		// the filtered pages plus the empty page ending the search
+		if got, want := device.Stats().SearchRequests, (len(want)+6)/7+1; got != want {
+			t.Errorf("search requests = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestSearchMessagesMarkerAndOffset(t *testing.T) {
+	device, server := newTestDevice(t, simulator.Options{Seed: 2, PageSize: 5})
+	c := newTestClient(server.URL)
+	all := device.Messages()
+
+	byOffset, err := c.SearchMessages(context.Background(), "", 5)
+	if err != nil {
+		t.Fatalf("SearchMessages() error = %v", err)
+	}
+	if got, want := markers(byOffset, time.Time{}, models.TimeMax), markers(all[5:10], time.Time{}, models.TimeMax); !slices.Equal(got, want) {
+		t.Errorf("page at offset 5 = %v, want %v", got, want)
+	}
+
+	// the marker takes precedence over the offset
+	byMarker, err := c.SearchMessages(context.Background(), all[11].Marker, 0)
+	if err != nil {
+		t.Fatalf("SearchMessages() error = %v", err)
+	}
+	if got, want := markers(byMarker, time.Time{}, models.TimeMax), markers(all[12:17], time.Time{}, models.TimeMax); !slices.Equal(got, want) {
+		t.Errorf("page after marker %s = %v, want %v", all[11].Marker, got, want)
+	}
+}
+
+func TestExpiringTokensAreRefreshed(t *testing.T) {
+	// tokens expiring within the renewal margin are refreshed before every request
+	device, server := newTestDevice(t, simulator.Options{Seed: 3, PageSize: 10, TokenLifetime: time.Second})
+
+	fetched := fetchAll(t, newTestClient(server.URL), time.Time{}, models.TimeMax)
+
+	if len(fetched) != len(device.Messages()) {
+		t.Errorf("fetched %d messages, want %d", len(fetched), len(device.Messages()))
+	}
+	stats := device.Stats()
+	if stats.Unauthorized != 0 {
+		t.Errorf("unauthorized requests = %d, want 0", stats.Unauthorized)
+	}
+	// one login, then a refresh before every further request
+	if want := stats.SearchRequests - 1; stats.Refreshes != want {
+		t.Errorf("refreshes = %d, want %d", stats.Refreshes, want)
+	}
+	if want := stats.Refreshes + 1; stats.TokenRequests != want {
+		t.Errorf("token requests = %d, want %d", stats.TokenRequests, want)
+	}
+}
+
+func TestRejectedTokenIsRefreshed(t *testing.T) {
+	device, server := newTestDevice(t, simulator.Options{Seed: 4, PageSize: 10})
+	c := newTestClient(server.URL)
+
+	var fetched []models.Message
+	err := c.FetchAllMessages(context.Background(), time.Time{}, models.TimeMax, func(messages []models.Message) bool {
+		if len(fetched) == 0 {
+			// e.g. the device restarted and forgot the issued tokens
+			device.ExpireTokens()
+		}
+		fetched = append(fetched, messages...)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("FetchAllMessages() error = %v", err)
+	}
+
+	if len(fetched) != len(device.Messages()) {
+		t.Errorf("fetched %d messages, want %d", len(fetched), len(device.Messages()))
+	}
+	stats := device.Stats()
+	if stats.Unauthorized != 1 || stats.Refreshes != 1 || stats.TokenRequests != 2 {
+		t.Errorf("stats = %+v, want 1 unauthorized request answered by 1 refresh", stats)
+	}
+}
+
+func TestTransientErrorsAreRetried(t *testing.T) {
+	device, server := newTestDevice(t, simulator.Options{Seed: 5, PageSize: 10})
+	// the 503 asks for a retry after one second with the Retry-After header
+	device.Fail(http.StatusServiceUnavailable, http.StatusBadGateway)
+
+	start := time.Now()
+	fetched := fetchAll(t, newTestClient(server.URL), time.Time{}, models.TimeMax)
+
+	if len(fetched) != len(device.Messages()) {
+		t.Errorf("fetched %d messages, want %d", len(fetched), len(device.Messages()))
+	}
+	if failures := device.Stats().Failures; failures != 2 {
+		t.Errorf("failures = %d, want 2", failures)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want the Retry-After delay of 1s", elapsed)
+	}
+}
+
+func TestRetriesAreLimited(t *testing.T) {
+	device, server := newTestDevice(t, simulator.Options{Seed: 6})
+	device.Fail(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	err := newTestClient(server.URL).FetchAllMessages(context.Background(), time.Time{}, models.TimeMax,
+		func([]models.Message) bool { return true })
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("FetchAllMessages() error = %v, want status 502", err)
+	}
+	if failures := device.Stats().Failures; failures != 3 {
+		t.Errorf("failures = %d, want 3 attempts", failures)
+	}
+}
+
+func TestPermanentErrorsAreNotRetried(t *testing.T) {
+	device, server := newTestDevice(t, simulator.Options{Seed: 7})
+	device.Fail(http.StatusInternalServerError)
+
+	err := newTestClient(server.URL).FetchAllMessages(context.Background(), time.Time{}, models.TimeMax,
+		func([]models.Message) bool { return true })
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("FetchAllMessages() error = %v, want status 500", err)
+	}
+	if requests := device.Stats().SearchRequests; requests != 1 {
+		t.Errorf("search requests = %d, want 1", requests)
+	}
+}
+
+func TestWrongCredentials(t *testing.T) {
+	device, server := newTestDevice(t, simulator.Options{Seed: 8})
+
+	err := NewWithOptions(server.URL, "user", "wrong", Options{}).FetchAllMessages(context.Background(),
+		time.Time{}, models.TimeMax, func([]models.Message) bool { return true })
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("FetchAllMessages() error = %v, want status 401", err)
+	}
+	if stats := device.Stats(); stats.TokenRequests != 1 || stats.SearchRequests != 0 {
+		t.Errorf("stats = %+v, want a single token request", stats)
+	}
+}
+
+func TestFingerprintMismatchIsNotRetried(t *testing.T) {
+	device := simulator.NewWithOptions(simulator.Options{Messages: simulator.Generate(9, testEnd, 1)})
+	server := httptest.NewTLSServer(device)
+	t.Cleanup(server.Close)
+
+	tlsConfig, err := NewTLSConfig(TLSOptions{Fingerprint: FormatFingerprint(make([]byte, 32))})
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+	c := NewWithOptions(server.URL, "user", "password", Options{
+		TLS: tlsConfig,
+		// a retry would exceed the deadline
+		Retry: RetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute, MaxDelay: time.Minute},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = c.FetchAllMessages(ctx, time.Time{}, models.TimeMax, func([]models.Message) bool { return true })
+	if !errors.Is(err, ErrFingerprintMismatch) {
+		t.Fatalf("FetchAllMessages() error = %v, want %v", err, ErrFingerprintMismatch)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("fingerprint mismatch was retried")
+	}
+	if device.Stats().TokenRequests != 0 {
+		t.Errorf("token was requested despite the fingerprint mismatch")
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// timestampLayout is the format of the from/until parameters of the search request
+const timestampLayout = "2006-01-02T15:04:05.000Z"
+
+// filterSupport is the result of probing whether the device filters messages itself
+type filterSupport int
+
+const (
+	filterUnknown filterSupport = iota
+	filterSupported
+	filterUnsupported
+)
+
+// searchFilter holds the parameters of the search request restricting the returned messages
+type searchFilter struct {
+	from      *string
+	until     *string
+	groupTags []int
+}
+
+// newSearchFilter creates the filter for the time range, open ends are omitted
+func newSearchFilter(from, until time.Time, groupTags []int) searchFilter {
+	filter := searchFilter{groupTags: groupTags}
+	if !from.IsZero() {
+		s := from.UTC().Format(timestampLayout)
+		filter.from = &s
+	}
+	if until.Before(models.TimeMax) {
+		s := until.UTC().Format(timestampLayout)
+		filter.until = &s
+	}
+	return filter
+}
+
+// empty reports whether the filter does not restrict the messages
+func (f searchFilter) empty() bool {
+	return f.from == nil && f.until == nil && len(f.groupTags) == 0
+}
+
+// matches reports whether the message is within the time range and message groups
+func matches(msg models.Message, from, until time.Time, groupTags []int) bool {
+	if msg.Timestamp.Before(from) || !msg.Timestamp.Before(until) {
+		return false
+	}
+	return len(groupTags) == 0 || slices.Contains(groupTags, msg.MessageGroupTag)
+}
+
+// probeFilter checks the first page fetched with a filter: devices whose firmware ignores
+// the filter return messages outside of it, from then on the messages are filtered locally
+func (c *Client) probeFilter(messages []models.Message, from, until time.Time) {
+	if c.serverFilter != filterUnknown || len(messages) == 0 {
+		return
+	}
+	for _, msg := range messages {
+		if !matches(msg, from, until, c.messageGroupTags) {
+			slog.Debug("device ignores the search filter, filtering locally", "timestamp", msg.Timestamp, "messageGroupTag", msg.MessageGroupTag)
+			c.serverFilter = filterUnsupported
+			return
+		}
+	}
+	slog.Debug("device supports the search filter")
+	c.serverFilter = filterSupported
+}
+
+// filterRejected reports whether the device rejected the filter parameters of the request
+func filterRejected(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusBadRequest || statusErr.StatusCode == http.StatusUnprocessableEntity)
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// DefaultRedactFields are the body fields redacted from trace logs by default
+var DefaultRedactFields = []string{"password", "access_token", "refresh_token", "refreshToken"}
+
+// requiredRedactFields are always redacted, even if missing from the configured fields
+var requiredRedactFields = []string{"password"}
+
+// redactedHeaders are always redacted from trace logs
+var redactedHeaders = map[string]struct{}{
+	"Authorization": {},
+	"Cookie":        {},
+	"Set-Cookie":    {},
+}
+
+// redactor removes sensitive fields from form-encoded and JSON bodies
+type redactor struct {
+	fields map[string]struct{}
+}
+
+// newRedactor creates a redactor for the field names (case-insensitive) and the required fields
+func newRedactor(fields []string) redactor {
+	r := redactor{fields: make(map[string]struct{}, len(fields)+len(requiredRedactFields))}
+	for _, field := range slices.Concat(requiredRedactFields, fields) {
+		r.fields[strings.ToLower(field)] = struct{}{}
+	}
+	return r
+}
+
+func (r redactor) isSensitive(field string) bool {
+	_, ok := r.fields[strings.ToLower(field)]
+	return ok
+}
+
+// body redacts a request or response body according to its content type
+func (r redactor) body(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return r.form(body)
+	case isJSON(mediaType):
+		return r.json(body)
+	case mediaType == "":
+		// unknown content type: redact whatever the body looks like
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return r.json(body)
+		}
+		return r.form(body)
+	default:
+		return body
+	}
+}
+
+// form redacts form-encoded values
+func (r redactor) form(body []byte) []byte {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+	return []byte(r.values(values))
+}
+
+// values encodes url values with the values of sensitive keys redacted
+func (r redactor) values(values url.Values) string {
+	keys := slices.Sorted(maps.Keys(values))
+
+	var sb strings.Builder
+	for _, key := range keys {
+		for _, value := range values[key] {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(url.QueryEscape(key))
+			sb.WriteByte('=')
+			if r.isSensitive(key) {
+				sb.WriteString(redacted)
+			} else {
+				sb.WriteString(url.QueryEscape(value))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// json redacts the values of sensitive keys at any depth; invalid JSON is replaced entirely,
+// as it cannot be inspected
+func (r redactor) json(body []byte) []byte {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return []byte("[unparsable body redacted]")
+	}
+	redactedBody, err := json.Marshal(r.walk(doc))
+	if err != nil {
+		return []byte("[unparsable body redacted]")
+	}
+	return redactedBody
+}
+
+func (r redactor) walk(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if r.isSensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = r.walk(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = r.walk(value)
+		}
+	}
+	return v
+}
+
+// url redacts sensitive query parameters
+func (r redactor) url(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = r.values(u.Query())
+	return redactedURL.String()
+}
+
+// headers returns the first value of each header with credentials redacted
+func (r redactor) headers(header map[string][]string) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		if _, ok := redactedHeaders[key]; ok {
+			headers[key] = redacted
+		} else {
+			headers[key] = values[0]
+		}
+	}
+	return headers
+}
+
+// isJSON reports whether the content type is JSON
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures retries of requests failing with transient errors
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per request (1 disables retries)
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled for each further retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries (a Retry-After header may request longer)
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used if no retry policy is configured
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+}
+
+// StatusError is returned for unexpected HTTP status codes
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by the Retry-After header (zero if absent)
+	RetryAfter time.Duration
+	message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s with status: %d", e.message, e.StatusCode)
+}
+
+// newStatusError creates a StatusError for the response
+func newStatusError(message string, resp *http.Response) *StatusError {
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		message:    message,
+	}
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// retry calls fn until it succeeds, fails with a permanent error or the attempts are exhausted
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		retryAfter, ok := retryable(err)
+		if !ok || attempt >= c.retryPolicy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay := max(c.retryPolicy.delay(attempt), retryAfter)
+		slog.Warn("request failed, retrying", "attempt", attempt, "delay", delay.Round(time.Millisecond), "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the exponential backoff with jitter before the retry following the attempt
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << min(attempt-1, 30)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	// jitter between half and the full delay, so concurrent clients don't retry in lockstep
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryable reports whether an error is transient and the delay requested by the server
+func retryable(err error) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return statusErr.RetryAfter, true
+		}
+		return 0, false
+	}
+
+	if isCertificateError(err) {
+		return 0, false
+	}
+
+	// timeouts, refused and reset connections and responses cut off while reading the body;
+	// other transport errors (e.g. unknown hosts or invalid URLs) are permanent
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return 0, true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	transportErr := func(err error) error {
+		return fmt.Errorf("failed to execute request: %w", &url.Error{Op: "Post", URL: "https://device.local", Err: err})
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{"service unavailable", &StatusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Second}, true, time.Second},
+		{"too many requests", &StatusError{StatusCode: http.StatusTooManyRequests}, true, 0},
+		{"bad gateway", &StatusError{StatusCode: http.StatusBadGateway}, true, 0},
+		{"unauthorized", &StatusError{StatusCode: http.StatusUnauthorized}, false, 0},
+		{"internal server error", &StatusError{StatusCode: http.StatusInternalServerError}, false, 0},
+		{"timeout", transportErr(os.ErrDeadlineExceeded), true, 0},
+		{"connection refused", transportErr(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true, 0},
+		{"connection reset", transportErr(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true, 0},
+		{"body cut off", fmt.Errorf("failed to decode response: %w", io.ErrUnexpectedEOF), true, 0},
+		{"unknown host", transportErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "device.local", IsNotFound: true}}), false, 0},
+		{"unsupported scheme", transportErr(errors.New(`unsupported protocol scheme "ftp"`)), false, 0},
+		{"fingerprint mismatch", transportErr(fmt.Errorf("%w: fingerprint 00, pinned 01", ErrFingerprintMismatch)), false, 0},
+		{"cancelled", transportErr(context.Canceled), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryAfter, ok := retryable(tt.err)
+			if ok != tt.retryable || retryAfter != tt.retryAfter {
+				t.Errorf("retryable(%v) = %s, %v, want %s, %v", tt.err, retryAfter, ok, tt.retryAfter, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-1", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 40: 5 * time.Second} {
+		// the jitter takes between half and the full delay
+		if got := policy.delay(attempt); got < want/2 || got > want {
+			t.Errorf("delay(%d) = %s, want between %s and %s", attempt, got, want/2, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrFingerprintMismatch is returned if the device certificate does not match the pinned fingerprint
+var ErrFingerprintMismatch = errors.New("device certificate does not match the pinned fingerprint")
+
+// TLSOptions contains options for verifying the device certificate
+type TLSOptions struct {
+	// Insecure disables certificate verification
+	Insecure bool
+	// CAFile is a PEM file with additional trusted certificates (e.g. the device's self-signed chain)
+	CAFile string
+	// Fingerprint pins the device certificate by its SHA-256 fingerprint (hex, colons optional).
+	// A pinned certificate is trusted without further chain verification.
+	Fingerprint string
+}
+
+// NewTLSConfig creates the TLS configuration for the options; by default certificates are verified
+// against the system trust store
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if opts.Insecure && (opts.CAFile != "" || opts.Fingerprint != "") {
+		return nil, errors.New("insecure cannot be combined with a CA certificate or fingerprint")
+	}
+
+	if opts.Insecure {
+		return &tls.Config{InsecureSkipVerify: true}, nil //nolint:gosec // explicitly requested by the user
+	}
+
+	if opts.Fingerprint != "" {
+		pinned, err := ParseFingerprint(opts.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			// the chain is not verified, instead the certificate must match the pinned fingerprint
+			InsecureSkipVerify: true, //nolint:gosec // verified in VerifyConnection
+			VerifyConnection: func(cs tls.ConnectionState) error {
+				if len(cs.PeerCertificates) == 0 {
+					return fmt.Errorf("%w: device presented no certificate", ErrFingerprintMismatch)
+				}
+				if actual := Fingerprint(cs.PeerCertificates[0]); !bytes.Equal(actual, pinned) {
+					return fmt.Errorf("%w: fingerprint %s, pinned %s",
+						ErrFingerprintMismatch, FormatFingerprint(actual), FormatFingerprint(pinned))
+				}
+				return nil
+			},
+		}, nil
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		return &tls.Config{RootCAs: pool}, nil
+	}
+
+	return &tls.Config{}, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a certificate
+func Fingerprint(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.Raw)
+	return sum[:]
+}
+
+// FormatFingerprint formats a fingerprint as colon separated upper case hex
+func FormatFingerprint(fingerprint []byte) string {
+	parts := make([]string, len(fingerprint))
+	for i, b := range fingerprint {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ParseFingerprint parses a SHA-256 fingerprint in hex, with or without colons
+func ParseFingerprint(s string) ([]byte, error) {
+	fingerprint, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(s), ":", ""))
+	if err != nil || len(fingerprint) != sha256.Size {
+		return nil, fmt.Errorf("fingerprint must be a SHA-256 hash in hex (64 digits, colons optional): %q", s)
+	}
+	return fingerprint, nil
+}
+
+// FetchCertificate connects to addr (host:port) without verification and returns the presented certificate chain.
+// The connection is aborted after timeout (no timeout if zero) or when ctx is cancelled.
+func FetchCertificate(ctx context.Context, addr string, timeout time.Duration) ([]*x509.Certificate, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config:    &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // only used to inspect the certificate
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s presented no certificate", addr)
+	}
+	return certs, nil
+}
+
+// isCertificateError reports whether err is caused by a failed certificate verification
+func isCertificateError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	return errors.As(err, &verificationErr) || errors.As(err, &hostnameErr) || errors.As(err, &authorityErr) ||
+		errors.Is(err, ErrFingerprintMismatch)
+}
+
+// wrapTLSError adds a hint on how to trust the device to certificate verification errors
+func wrapTLSError(err error) error {
+	if isCertificateError(err) {
+		return fmt.Errorf("%w (the device certificate is not trusted: run the trust command to pin its fingerprint, use --ca-cert, or --insecure)", err)
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseFingerprint(t *testing.T) {
+	hex := strings.Repeat("AB", 32)
+	colons := strings.TrimSuffix(strings.Repeat("ab:", 32), ":")
+
+	for _, s := range []string{hex, colons, " " + colons + "\n"} {
+		fingerprint, err := ParseFingerprint(s)
+		if err != nil {
+			t.Fatalf("ParseFingerprint(%q) error = %v", s, err)
+		}
+		if formatted := FormatFingerprint(fingerprint); formatted != strings.ToUpper(colons) {
+			t.Errorf("FormatFingerprint(ParseFingerprint(%q)) = %s, want %s", s, formatted, strings.ToUpper(colons))
+		}
+	}
+	for _, s := range []string{"", "AB:CD", hex + "AB", strings.Repeat("XY", 32)} {
+		if _, err := ParseFingerprint(s); err == nil {
+			t.Errorf("ParseFingerprint(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	cert := server.Certificate()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		trusted bool
+	}{
+		{"system trust store", TLSOptions{}, false},
+		{"insecure", TLSOptions{Insecure: true}, true},
+		{"ca file", TLSOptions{CAFile: caFile}, true},
+		{"pinned fingerprint", TLSOptions{Fingerprint: FormatFingerprint(Fingerprint(cert))}, true},
+		{"other fingerprint", TLSOptions{Fingerprint: FormatFingerprint(make([]byte, 32))}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewTLSConfig(tt.opts)
+			if err != nil {
+				t.Fatalf("NewTLSConfig() error = %v", err)
+			}
+			resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: config}}).Get(server.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			if trusted := err == nil; trusted != tt.trusted {
+				t.Errorf("request error = %v, want trusted %v", err, tt.trusted)
+			}
+			if err != nil && !isCertificateError(err) {
+				t.Errorf("isCertificateError(%v) = false", err)
+			}
+		})
+	}
+
+	if _, err := NewTLSConfig(TLSOptions{Insecure: true, CAFile: caFile}); err == nil {
+		t.Error("NewTLSConfig() with insecure and CA file succeeded, want an error")
+	}
+}
+
+func TestFetchCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	certs, err := FetchCertificate(context.Background(), server.Listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("FetchCertificate() error = %v", err)
+	}
+	if !certs[0].Equal(server.Certificate()) {
+		t.Errorf("FetchCertificate() = %s, want the server certificate", certs[0].Subject)
+	}
+}
+
+func TestFetchCertificateOfSilentDevice(t *testing.T) {
+	// accepts connections, but never answers the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		_ = listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	addr := listener.Addr().String()
+
+	start := time.Now()
+	if _, err := FetchCertificate(context.Background(), addr, 100*time.Millisecond); err == nil {
+		t.Error("FetchCertificate() succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("FetchCertificate() timed out after %s, want 100ms", elapsed)
+	}
+
+	// e.g. interrupted with Ctrl-C
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := FetchCertificate(ctx, addr, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("FetchCertificate() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// unsafeChars matches characters not used in cache filenames
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// TokenCache stores the token of a device between runs in a user-private file
+type TokenCache struct {
+	path string
+}
+
+// NewTokenCache creates a token cache stored at path
+func NewTokenCache(path string) *TokenCache {
+	return &TokenCache{path: path}
+}
+
+// DefaultTokenCachePath returns the default cache path for a device host and username
+// ($XDG_CACHE_HOME/sma_chg_log/tokens/<host>_<username>.json, defaulting to ~/.cache)
+func DefaultTokenCachePath(host, username string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine token cache directory: %w", err)
+	}
+
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	name := unsafeChars.ReplaceAllString(host, "_") + "_" + unsafeChars.ReplaceAllString(username, "_") + ".json"
+	return filepath.Join(dir, "sma_chg_log", "tokens", name), nil
+}
+
+// Path returns the path of the cache file
+func (c *TokenCache) Path() string {
+	return c.path
+}
+
+// load reads the cached token, an empty token is returned if there is none
+func (c *TokenCache) load() (token, error) {
+	var cached token
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cached, nil
+	}
+	if err != nil {
+		return cached, err
+	}
+
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return token{}, fmt.Errorf("invalid token cache: %w", err)
+	}
+	return cached, nil
+}
+
+// save writes the token readable by the current user only, replacing the file atomically
+func (c *TokenCache) save(t token) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".token-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	// CreateTemp creates the file with mode 0600
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Delete removes the cached token
+func (c *TokenCache) Delete() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
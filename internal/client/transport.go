@@ -11,14 +11,17 @@ import (
 )
 
 // loggingTransport wraps an http.RoundTripper and logs requests/responses at trace level
+// with sensitive header and body fields redacted
 type loggingTransport struct {
 	transport http.RoundTripper
+	redactor  redactor
 }
 
-// newLoggingTransport creates a new logging transport wrapper
-func newLoggingTransport(transport http.RoundTripper) *loggingTransport {
+// newLoggingTransport creates a new logging transport wrapper redacting the given body fields
+func newLoggingTransport(transport http.RoundTripper, redactFields []string) *loggingTransport {
 	return &loggingTransport{
 		transport: transport,
+		redactor:  newRedactor(redactFields),
 	}
 }
 
@@ -61,34 +64,20 @@ func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 }
 
 func (t *loggingTransport) logRequest(req *http.Request, body []byte) {
-	headers := make(map[string]string)
-	for key, values := range req.Header {
-		if key == "Authorization" {
-			headers[key] = "[REDACTED]"
-		} else {
-			headers[key] = values[0]
-		}
-	}
-
 	slog.Log(req.Context(), log.LevelTrace, "request",
 		"method", req.Method,
 		"path", req.URL.Path,
-		"url", req.URL.String(),
-		"headers", headers,
-		"payload", string(body),
+		"url", t.redactor.url(req.URL),
+		"headers", t.redactor.headers(req.Header),
+		"payload", string(t.redactor.body(req.Header.Get("Content-Type"), body)),
 	)
 }
 
 func (t *loggingTransport) logResponse(resp *http.Response, body []byte) {
-	headers := make(map[string]string)
-	for key, values := range resp.Header {
-		headers[key] = values[0]
-	}
-
 	slog.Log(resp.Request.Context(), log.LevelTrace, "response",
 		"status", resp.StatusCode,
 		"path", resp.Request.URL.Path,
-		"headers", headers,
-		"payload", string(body),
+		"headers", t.redactor.headers(resp.Header),
+		"payload", string(t.redactor.body(resp.Header.Get("Content-Type"), body)),
 	)
 }
@@ -0,0 +1,63 @@
+// Package credentials stores device passwords per host and username, in the system keyring
+// or an encrypted file
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// service is the name the passwords are stored under
+const service = "sma_chg_log"
+
+// Backends of the password store
+const (
+	BackendSystem = "system"
+	BackendFile   = "file"
+	BackendNone   = "none"
+)
+
+// Backends are the names of the available backends
+var Backends = []string{BackendSystem, BackendFile, BackendNone}
+
+// ErrNotFound is returned if no password is stored for the host and username
+var ErrNotFound = errors.New("no stored password")
+
+// Store stores passwords per host and username
+type Store interface {
+	// Get returns the stored password (ErrNotFound if there is none)
+	Get(host, username string) (string, error)
+	// Set stores the password, replacing a stored one
+	Set(host, username, password string) error
+	// Delete removes the stored password
+	Delete(host, username string) error
+	// String describes the store for messages
+	String() string
+}
+
+// Open opens the store of the backend, the file backend uses the file and passphrase
+func Open(backend, path, passphrase string) (Store, error) {
+	switch backend {
+	case BackendSystem:
+		return NewSystem(), nil
+	case BackendFile:
+		if path == "" {
+			var err error
+			if path, err = DefaultFilePath(); err != nil {
+				return nil, err
+			}
+		}
+		return NewFile(path, passphrase), nil
+	case BackendNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid keyring %q (valid: %s)", backend, strings.Join(Backends, ", "))
+	}
+}
+
+// key identifies the password of a username on a host (regardless of the scheme)
+func key(host, username string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return username + "@" + host
+}
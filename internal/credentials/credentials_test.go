@@ -0,0 +1,83 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestOpen(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		backend string
+		want    string
+		wantErr bool
+	}{
+		{backend: BackendSystem, want: "system keyring"},
+		{backend: BackendFile, want: filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "sma_chg_log", "credentials.enc")},
+		{backend: BackendNone},
+		{backend: "vault", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			store, err := Open(tt.backend, "", "secret")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.want == "" {
+				if store != nil {
+					t.Errorf("Open() = %s, want no store", store)
+				}
+				return
+			}
+			if store == nil || !strings.Contains(store.String(), tt.want) {
+				t.Errorf("Open() = %v, want a store containing %q", store, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	// the scheme doesn't matter
+	if a, b := key("https://device.local", "admin"), key("device.local", "admin"); a != b {
+		t.Errorf("key() = %s and %s, want the same key", a, b)
+	}
+	if a, b := key("device.local", "admin"), key("device.local", "user"); a == b {
+		t.Errorf("key() = %s for different users", a)
+	}
+}
+
+// testStore stores, replaces and deletes a password
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	if _, err := store.Get("device.local", "admin"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a missing password error = %v, want %v", err, ErrNotFound)
+	}
+	for _, password := range []string{"first", "second"} {
+		if err := store.Set("https://device.local", "admin", password); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if got, err := store.Get("device.local", "admin"); err != nil || got != password {
+			t.Fatalf("Get() = %q, %v, want %q", got, err, password)
+		}
+	}
+	if err := store.Delete("device.local", "admin"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("device.local", "admin"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a deleted password error = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete("device.local", "admin"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() of a deleted password error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestSystem(t *testing.T) {
+	keyring.MockInit()
+	testStore(t, NewSystem())
+}
@@ -0,0 +1,201 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	fileVersion = 1
+	// iterations of PBKDF2-SHA256 deriving the key from the passphrase
+	iterations = 600_000
+	saltSize   = 16
+	keySize    = 32
+)
+
+// additionalData binds the ciphertext to the file format
+var additionalData = []byte("sma_chg_log credentials v1")
+
+// File stores passwords in a file encrypted with AES-GCM, using a key derived from a passphrase.
+// It works without a system keyring, e.g. on headless machines and in containers.
+type File struct {
+	path       string
+	passphrase string
+}
+
+// encryptedFile is the format of the file
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// NewFile creates a store in the file at path encrypted with the passphrase
+func NewFile(path, passphrase string) *File {
+	return &File{path: path, passphrase: passphrase}
+}
+
+// DefaultFilePath returns the default path of the encrypted file
+// ($XDG_CONFIG_HOME/sma_chg_log/credentials.enc, defaulting to ~/.config)
+func DefaultFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine credentials directory: %w", err)
+	}
+	return filepath.Join(dir, "sma_chg_log", "credentials.enc"), nil
+}
+
+// Get implements Store
+func (f *File) Get(host, username string) (string, error) {
+	passwords, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	password, ok := passwords[key(host, username)]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return password, nil
+}
+
+// Set implements Store
+func (f *File) Set(host, username, password string) error {
+	passwords, err := f.load()
+	if err != nil {
+		return err
+	}
+	passwords[key(host, username)] = password
+	return f.save(passwords)
+}
+
+// Delete implements Store
+func (f *File) Delete(host, username string) error {
+	passwords, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := passwords[key(host, username)]; !ok {
+		return ErrNotFound
+	}
+	delete(passwords, key(host, username))
+	return f.save(passwords)
+}
+
+func (f *File) String() string {
+	return "encrypted file " + f.path
+}
+
+// load decrypts the passwords, a missing file holds none
+func (f *File) load() (map[string]string, error) {
+	if f.passphrase == "" {
+		return nil, errors.New("the encrypted credentials file needs a passphrase (SMA_KEYRING_PASSPHRASE)")
+	}
+
+	passwords := make(map[string]string)
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return passwords, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", f.path, err)
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported credentials file version %d", file.Version)
+	}
+
+	aead, err := f.cipher(file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: wrong passphrase or corrupted file", f.path)
+	}
+
+	if err := json.Unmarshal(plaintext, &passwords); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", f.path, err)
+	}
+	return passwords, nil
+}
+
+// save encrypts the passwords with a new salt and nonce and replaces the file atomically
+func (f *File) save(passwords map[string]string) error {
+	plaintext, err := json.Marshal(passwords)
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{
+		Version:    fileVersion,
+		Iterations: iterations,
+		Salt:       make([]byte, saltSize),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	aead, err := f.cipher(file.Salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Data = aead.Seal(nil, file.Nonce, plaintext, additionalData)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	// CreateTemp creates the file with mode 0600
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// cipher derives the key from the passphrase and creates the AES-GCM cipher
+func (f *File) cipher(salt []byte, iterations int) (cipher.AEAD, error) {
+	derived, err := pbkdf2.Key(sha256.New, f.passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
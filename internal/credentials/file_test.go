@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sma_chg_log", "credentials.enc")
+	testStore(t, NewFile(path, "passphrase"))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("file mode = %o, want 600", mode)
+	}
+}
+
+func TestFileIsEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	if err := NewFile(path, "passphrase").Set("device.local", "admin", "s3cr3t-pa55"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cr3t-pa55") || strings.Contains(string(data), "admin") {
+		t.Errorf("file contains the plain password: %s", data)
+	}
+
+	if _, err := NewFile(path, "wrong").Get("device.local", "admin"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Get() with a wrong passphrase error = %v, want a decryption error", err)
+	}
+	if _, err := NewFile(path, "").Get("device.local", "admin"); err == nil || !strings.Contains(err.Error(), "SMA_KEYRING_PASSPHRASE") {
+		t.Errorf("Get() without passphrase error = %v, want a hint on the passphrase", err)
+	}
+}
@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// System stores passwords in the system keyring (Secret Service on Linux, Keychain on macOS,
+// Credential Manager on Windows)
+type System struct{}
+
+// NewSystem creates a store using the system keyring
+func NewSystem() *System {
+	return &System{}
+}
+
+// Get implements Store
+func (s *System) Get(host, username string) (string, error) {
+	password, err := keyring.Get(service, key(host, username))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return password, err
+}
+
+// Set implements Store
+func (s *System) Set(host, username, password string) error {
+	return keyring.Set(service, key(host, username), password)
+}
+
+// Delete implements Store
+func (s *System) Delete(host, username string) error {
+	err := keyring.Delete(service, key(host, username))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *System) String() string {
+	return "system keyring"
+}
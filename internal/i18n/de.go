@@ -0,0 +1,78 @@
+package i18n
+
+// german translates the texts of the reports to German
+var german = map[string]string{
+	// PDF
+	"CHARGING HISTORY OVERVIEW": "ÜBERSICHT LADEVORGÄNGE",
+	"Page %d of %s":             "Seite %d von %s",
+	"Created On:":               "Erstellt am:",
+	"Overview Period:":          "Zeitraum:",
+	"Total Charging Records:":   "Ladevorgänge gesamt:",
+	"Total Consumption:":        "Verbrauch gesamt:",
+	"Total Cost:":               "Kosten gesamt:",
+	"Ongoing Sessions:":         "Laufende Ladevorgänge:",
+	"Incomplete Records:":       "Unvollständige Einträge:",
+	"Record\nDate":              "Buchungs-\ndatum",
+	"Started at\nEnded at":      "Beginn\nEnde",
+	"Started at":                "Beginn",
+	"Elapsed":                   "Dauer",
+	"(missing start)":           "(Beginn fehlt)",
+	"(missing stop)":            "(Ende fehlt)",
+	"(%s%% share)":              "(%s %% Anteil)",
+	"%dh %02dm":                 "%d h %02d min",
+	"ONGOING SESSIONS":          "LAUFENDE LADEVORGÄNGE",
+	"SUBTOTALS PER CHARGER":     "ZWISCHENSUMMEN JE LADESTATION",
+
+	// PDF and XLSX
+	"Record Date":       "Buchungsdatum",
+	"Charger":           "Ladestation",
+	"Device":            "Gerät",
+	"Authentication":    "Authentifizierung",
+	"Start":             "Beginn",
+	"End":               "Ende",
+	"Consumption (kWh)": "Verbrauch (kWh)",
+	"Status":            "Status",
+	"Anomaly":           "Anomalie",
+	"Share":             "Anteil",
+	"Cost (%s)":         "Kosten (%s)",
+	"Employee Number":   "Personalnummer",
+	"Cost Center":       "Kostenstelle",
+	"Vehicle":           "Fahrzeug",
+	"License Plate":     "Kennzeichen",
+	"Sessions":          "Ladevorgänge",
+
+	// XLSX status and anomaly values, the CSV keeps them as machine values
+	"completed":     "abgeschlossen",
+	"ongoing":       "laufend",
+	"incomplete":    "unvollständig",
+	"missing-start": "Beginn fehlt",
+	"missing-stop":  "Ende fehlt",
+
+	// XLSX
+	"Summary":                 "Zusammenfassung",
+	"Created On":              "Erstellt am",
+	"Overview Period From":    "Zeitraum von",
+	"Overview Period Until":   "Zeitraum bis",
+	"Total Charging Records":  "Ladevorgänge gesamt",
+	"Total Consumption (kWh)": "Verbrauch gesamt (kWh)",
+	"Total Cost (%s)":         "Kosten gesamt (%s)",
+	"Ongoing Sessions":        "Laufende Ladevorgänge",
+
+	// CSV header
+	"record date":     "Buchungsdatum",
+	"charger name":    "Ladestation",
+	"authentication":  "Authentifizierung",
+	"start":           "Beginn",
+	"end":             "Ende",
+	"consumption":     "Verbrauch",
+	"status":          "Status",
+	"anomaly":         "Anomalie",
+	"share":           "Anteil",
+	"device":          "Gerät",
+	"cost":            "Kosten",
+	"currency":        "Währung",
+	"employee number": "Personalnummer",
+	"cost center":     "Kostenstelle",
+	"vehicle":         "Fahrzeug",
+	"license plate":   "Kennzeichen",
+}
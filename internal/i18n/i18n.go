@@ -0,0 +1,105 @@
+// Package i18n translates the texts of the reports and formats numbers and dates per language
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLanguage is the language of the texts in the source code
+const DefaultLanguage = "en"
+
+// Languages are the supported languages
+var Languages = []string{"en", "de"}
+
+// Locale translates texts and formats numbers and dates in a language
+type Locale struct {
+	Language string
+	// DateLayout and DateTimeLayout are the Go layouts of dates and timestamps
+	DateLayout     string
+	DateTimeLayout string
+	decimal        string
+	thousands      string
+	// texts maps the English texts to their translation, missing texts are kept in English
+	texts map[string]string
+}
+
+var locales = map[string]*Locale{
+	"en": {
+		Language:       "en",
+		DateLayout:     "2006-01-02",
+		DateTimeLayout: "2006-01-02 15:04:05",
+		decimal:        ".",
+		thousands:      ",",
+	},
+	"de": {
+		Language:       "de",
+		DateLayout:     "02.01.2006",
+		DateTimeLayout: "02.01.2006 15:04:05",
+		decimal:        ",",
+		thousands:      ".",
+		texts:          german,
+	},
+}
+
+// Lookup returns the locale of the language
+func Lookup(language string) (*Locale, error) {
+	locale, ok := locales[strings.ToLower(language)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q (supported: %s)", language, strings.Join(Languages, ", "))
+	}
+	return locale, nil
+}
+
+// Default returns the locale of the default language
+func Default() *Locale {
+	return locales[DefaultLanguage]
+}
+
+// T translates the text; with arguments, the translation is a format for fmt.Sprintf
+func (l *Locale) T(text string, args ...any) string {
+	if translated, ok := l.texts[text]; ok {
+		text = translated
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Number formats v with the decimals and the separators of the language, e.g. 1,234.50 or 1.234,50
+func (l *Locale) Number(v float64, decimals int) string {
+	formatted := strconv.FormatFloat(v, 'f', decimals, 64)
+
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	integer, fraction, _ := strings.Cut(formatted, ".")
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(l.thousands)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(l.decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Date formats the date of t
+func (l *Locale) Date(t time.Time) string {
+	return t.Format(l.DateLayout)
+}
+
+// DateTime formats the date and time of t
+func (l *Locale) DateTime(t time.Time) string {
+	return t.Format(l.DateTimeLayout)
+}
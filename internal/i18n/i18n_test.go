@@ -0,0 +1,92 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	for _, language := range Languages {
+		if locale, err := Lookup(language); err != nil || locale.Language != language {
+			t.Errorf("Lookup(%q) = %v, %v", language, locale, err)
+		}
+	}
+	if locale, err := Lookup("DE"); err != nil || locale.Language != "de" {
+		t.Errorf("Lookup(\"DE\") = %v, %v, want de", locale, err)
+	}
+	if _, err := Lookup("fr"); err == nil {
+		t.Error("Lookup(\"fr\") succeeded, want an error")
+	}
+}
+
+func TestT(t *testing.T) {
+	german, err := Lookup("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		locale *Locale
+		text   string
+		args   []any
+		want   string
+	}{
+		{Default(), "Total Cost (%s)", []any{"EUR"}, "Total Cost (EUR)"},
+		{german, "Total Cost (%s)", []any{"EUR"}, "Kosten gesamt (EUR)"},
+		{german, "Page %d of %s", []any{2, "{nb}"}, "Seite 2 von {nb}"},
+		{german, "missing-stop", nil, "Ende fehlt"},
+		// missing texts are kept in English
+		{german, "untranslated", nil, "untranslated"},
+		{german, "", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.locale.T(tt.text, tt.args...); got != tt.want {
+			t.Errorf("%s T(%q) = %q, want %q", tt.locale.Language, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	german, err := Lookup("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		v        float64
+		decimals int
+		en, de   string
+	}{
+		{0, 2, "0.00", "0,00"},
+		{12.5, 1, "12.5", "12,5"},
+		{1234.567, 2, "1,234.57", "1.234,57"},
+		{-1234567.8, 0, "-1,234,568", "-1.234.568"},
+		{999, 0, "999", "999"},
+	}
+	for _, tt := range tests {
+		if got := Default().Number(tt.v, tt.decimals); got != tt.en {
+			t.Errorf("en Number(%v, %d) = %s, want %s", tt.v, tt.decimals, got, tt.en)
+		}
+		if got := german.Number(tt.v, tt.decimals); got != tt.de {
+			t.Errorf("de Number(%v, %d) = %s, want %s", tt.v, tt.decimals, got, tt.de)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	german, err := Lookup("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 31, 18, 5, 37, 0, time.UTC)
+
+	if got := Default().Date(at); got != "2026-01-31" {
+		t.Errorf("en Date() = %s", got)
+	}
+	if got := german.Date(at); got != "31.01.2026" {
+		t.Errorf("de Date() = %s", got)
+	}
+	if got := german.DateTime(at); got != "31.01.2026 18:05:37" {
+		t.Errorf("de DateTime() = %s", got)
+	}
+}
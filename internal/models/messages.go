@@ -1,10 +1,17 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
 	"time"
 )
 
+// Message IDs of the charging events
+const (
+	MessageIDChargingStarted   = 9812
+	MessageIDChargingCompleted = 9813
+)
+
 // SearchRequest represents the POST body for the messages search endpoint
 type SearchRequest struct {
 	ComponentID      string   `json:"componentId"`
@@ -36,12 +43,16 @@ func (a *MessageArgument) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, aux); err != nil {
 		return err
 	}
-	a.RawJSON = data
+	a.RawJSON = append(json.RawMessage(nil), data...)
 	return nil
 }
 
 // MarshalJSON returns the original raw JSON
 func (a *MessageArgument) MarshalJSON() ([]byte, error) {
+	if a.RawJSON == nil {
+		type Alias MessageArgument
+		return json.Marshal((*Alias)(a))
+	}
 	return a.RawJSON, nil
 }
 
@@ -60,6 +71,9 @@ type Message struct {
 	Timestamp          time.Time         `json:"timestamp"`
 	TraceLevel         string            `json:"traceLevel"`
 	RawJSON            json.RawMessage   `json:"-"`
+	// Source is the name of the device the message was fetched from (set when fetching from several devices).
+	// It is not part of the device's response and kept in exported and archived messages as sourceDevice.
+	Source string `json:"sourceDevice,omitempty"`
 }
 
 // UnmarshalJSON implements custom unmarshalling to capture raw JSON
@@ -73,16 +87,40 @@ func (m *Message) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, aux); err != nil {
 		return err
 	}
-	m.RawJSON = data
+	m.RawJSON = append(json.RawMessage(nil), data...)
 	return nil
 }
 
-// MarshalJSON returns the original raw JSON
+// MarshalJSON returns the original raw JSON, with the source device added if not contained yet
 func (m *Message) MarshalJSON() ([]byte, error) {
-	return m.RawJSON, nil
+	if m.RawJSON == nil {
+		type Alias Message
+		return json.Marshal((*Alias)(m))
+	}
+	if m.Source == "" {
+		return m.RawJSON, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(m.RawJSON, &fields); err != nil {
+		return nil, err
+	}
+	source, err := json.Marshal(m.Source)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(fields["sourceDevice"], source) {
+		return m.RawJSON, nil
+	}
+	fields["sourceDevice"] = source
+	return json.Marshal(fields)
 }
 
 // TokenResponse represents the response from the token endpoint
 type TokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn is the lifetime of the access token in seconds (zero if unknown)
+	ExpiresIn int `json:"expires_in"`
 }
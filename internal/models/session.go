@@ -1,16 +1,93 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 var (
 	TimeMax = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
 )
 
-// ChargingSession represents a paired charging start/stop event
+// Anomaly flags a charging session that could not be paired cleanly
+type Anomaly string
+
+const (
+	// AnomalyMissingStart marks a session whose start event is missing
+	AnomalyMissingStart Anomaly = "missing-start"
+	// AnomalyMissingStop marks a session whose stop event is missing
+	AnomalyMissingStop Anomaly = "missing-stop"
+)
+
+// SessionStatus describes the state of a charging session
+type SessionStatus string
+
+const (
+	// StatusCompleted marks a session with both start and stop event
+	StatusCompleted SessionStatus = "completed"
+	// StatusOngoing marks a session that is still charging
+	StatusOngoing SessionStatus = "ongoing"
+	// StatusIncomplete marks a session with a missing start or stop event (see Anomaly)
+	StatusIncomplete SessionStatus = "incomplete"
+)
+
+// Duration is a time.Duration that is encoded as string (e.g. "1h30m0s") in JSON
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// User holds metadata of the user behind an authentication value
+type User struct {
+	Name           string `json:"name,omitempty"`
+	EmployeeNumber string `json:"employeeNumber,omitempty"`
+	CostCenter     string `json:"costCenter,omitempty"`
+	Vehicle        string `json:"vehicle,omitempty"`
+	LicensePlate   string `json:"licensePlate,omitempty"`
+}
+
+// HasMetadata reports whether any field besides the name is set
+func (u User) HasMetadata() bool {
+	return u.EmployeeNumber != "" || u.CostCenter != "" || u.Vehicle != "" || u.LicensePlate != ""
+}
+
+// ChargingSession represents a paired charging start/stop event.
+// The consumption of ongoing sessions is not yet known and omitted from their JSON.
 type ChargingSession struct {
-	ChargerName    string    `json:"chargerName"`
-	Consumption    float64   `json:"consumption"`
-	Authentication string    `json:"authentication,omitzero"`
-	Start          time.Time `json:"start,omitzero"`
-	End            time.Time `json:"end"`
+	ChargerName    string        `json:"chargerName"`
+	Device         string        `json:"device,omitempty"`
+	Consumption    float64       `json:"consumption"`
+	Authentication string        `json:"authentication,omitzero"`
+	User           *User         `json:"user,omitempty"`
+	Start          time.Time     `json:"start,omitzero"`
+	End            time.Time     `json:"end,omitzero"`
+	Status         SessionStatus `json:"status"`
+	Elapsed        Duration      `json:"elapsed,omitzero"`
+	Anomaly        Anomaly       `json:"anomaly,omitzero"`
+	Share          float64       `json:"share,omitzero"`
+	Cost           *float64      `json:"cost,omitempty"`
+	Currency       string        `json:"currency,omitempty"`
+}
+
+// MarshalJSON encodes the session, omitting the unknown consumption of ongoing sessions
+func (s ChargingSession) MarshalJSON() ([]byte, error) {
+	type session ChargingSession
+	var consumption *float64
+	if s.Status != StatusOngoing {
+		consumption = &s.Consumption
+	}
+	return json.Marshal(struct {
+		session
+		Consumption *float64 `json:"consumption,omitempty"`
+	}{session(s), consumption})
+}
+
+// RecordTime returns the time the session is recorded at: its end, or its start if the end is unknown
+func (s ChargingSession) RecordTime() time.Time {
+	if s.End.IsZero() {
+		return s.Start
+	}
+	return s.End
 }
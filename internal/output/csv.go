@@ -1,57 +1,138 @@
 package output
 
 import (
-	"encoding/csv"
+	"bufio"
 	"io"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/joshiste/sma_chg_log/internal/models"
 )
 
-// CSVFormatter outputs charging session as CSV
+// CSVFormatter outputs charging session as CSV in the dialect of the options.
+// It writes no totals row, so every row is a session for spreadsheets and imports.
 type CSVFormatter struct {
-	writer *csv.Writer
+	writer  *bufio.Writer
+	dialect CSVDialect
+	opts    Options
 }
 
 // NewCSVFormatter creates a new CSV formatter
 func NewCSVFormatter(w io.Writer) *CSVFormatter {
+	return NewCSVFormatterWithOptions(w, Options{})
+}
+
+// NewCSVFormatterWithOptions creates a new CSV formatter with options
+func NewCSVFormatterWithOptions(w io.Writer, opts Options) *CSVFormatter {
+	dialect := opts.CSV
+	if dialect == (CSVDialect{}) {
+		dialect = DefaultCSVDialect
+	}
 	return &CSVFormatter{
-		writer: csv.NewWriter(w),
+		writer:  bufio.NewWriter(w),
+		dialect: dialect,
+		opts:    opts,
 	}
 }
 
-// WriteHeader writes the CSV header row
+// WriteHeader writes the byte order mark (if enabled) and the CSV header row
 func (f *CSVFormatter) WriteHeader() error {
-	return f.writer.Write([]string{
+	if f.dialect.BOM {
+		if _, err := f.writer.WriteString("\uFEFF"); err != nil {
+			return err
+		}
+	}
+
+	header := []string{
 		"record date",
 		"charger name",
 		"authentication",
 		"start",
 		"end",
 		"consumption",
-	})
+		"status",
+		"anomaly",
+		"share",
+	}
+	if f.opts.DeviceColumn {
+		header = append(header, "device")
+	}
+	if f.opts.Currency != "" {
+		header = append(header, "cost", "currency")
+	}
+	if f.opts.UserColumns {
+		header = append(header, "employee number", "cost center", "vehicle", "license plate")
+	}
+	for i, name := range header {
+		header[i] = f.dialect.header(name)
+	}
+	return f.write(header)
 }
 
 // WriteSession writes a charging session as a CSV row
 func (f *CSVFormatter) WriteSession(session models.ChargingSession) error {
 	start := ""
 	if !session.Start.IsZero() {
-		start = session.Start.Format(time.RFC3339)
+		start = f.opts.in(session.Start).Format(f.dialect.TimeLayout)
+	}
+
+	end := ""
+	if !session.End.IsZero() {
+		end = f.opts.in(session.End).Format(f.dialect.TimeLayout)
 	}
 
-	return f.writer.Write([]string{
-		session.End.Format("2006-01-02"),
+	consumption := ""
+	if session.Status != models.StatusOngoing {
+		consumption = f.dialect.number(strconv.FormatFloat(session.Consumption, 'f', 2, 64))
+	}
+
+	share := ""
+	if session.Share != 0 {
+		share = f.dialect.number(strconv.FormatFloat(session.Share, 'f', 4, 64))
+	}
+
+	record := []string{
+		f.opts.recordDate(session).Format(f.dialect.DateLayout),
 		session.ChargerName,
 		session.Authentication,
 		start,
-		session.End.Format(time.RFC3339),
-		strconv.FormatFloat(session.Consumption, 'f', 2, 64),
-	})
+		end,
+		consumption,
+		string(session.Status),
+		string(session.Anomaly),
+		share,
+	}
+	if f.opts.DeviceColumn {
+		record = append(record, session.Device)
+	}
+	if f.opts.Currency != "" {
+		cost := ""
+		if session.Cost != nil {
+			cost = f.dialect.number(strconv.FormatFloat(*session.Cost, 'f', f.opts.CostDecimals, 64))
+		}
+		record = append(record, cost, session.Currency)
+	}
+	if f.opts.UserColumns {
+		var user models.User
+		if session.User != nil {
+			user = *session.User
+		}
+		record = append(record, user.EmployeeNumber, user.CostCenter, user.Vehicle, user.LicensePlate)
+	}
+	return f.write(record)
+}
+
+// write writes a row with the fields quoted according to the dialect
+func (f *CSVFormatter) write(fields []string) error {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = f.dialect.field(field)
+	}
+	_, err := f.writer.WriteString(strings.Join(quoted, string(f.dialect.Delimiter)) + "\n")
+	return err
 }
 
 // Flush ensures all buffered data is written
 func (f *CSVFormatter) Flush() error {
-	f.writer.Flush()
-	return f.writer.Error()
+	return f.writer.Flush()
 }
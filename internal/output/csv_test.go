@@ -0,0 +1,56 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+func TestCSVFormatter(t *testing.T) {
+	sessions := simulatedSessions(t)
+
+	records, err := csv.NewReader(bytes.NewReader(format(t, "csv", sessions, Options{}))).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+
+	wantHeader := "record date,charger name,authentication,start,end,consumption,status,anomaly,share"
+	if header := strings.Join(records[0], ","); header != wantHeader {
+		t.Errorf("header = %s, want %s", header, wantHeader)
+	}
+	if len(records) != len(sessions)+1 {
+		t.Fatalf("%d rows, want %d", len(records)-1, len(sessions))
+	}
+	for i, record := range records[1:] {
+		session := sessions[i]
+		if record[1] != session.ChargerName || record[2] != session.Authentication || record[6] != string(session.Status) {
+			t.Errorf("row %d = %v, want session %+v", i+1, record, session)
+		}
+		if ongoing := session.Status == models.StatusOngoing; (record[5] == "") != ongoing {
+			t.Errorf("row %d consumption = %q, want it empty only for ongoing sessions", i+1, record[5])
+		}
+	}
+}
+
+func TestCSVFormatterDialect(t *testing.T) {
+	sessions := []models.ChargingSession{{
+		ChargerName:    "Garage; left",
+		Authentication: "Müller",
+		Start:          testFrom.Add(8 * time.Hour),
+		End:            testFrom.Add(10 * time.Hour),
+		Consumption:    12.5,
+		Status:         models.StatusCompleted,
+	}}
+
+	out := format(t, "csv", sessions, Options{CSV: CSVDialects["excel-de"], Location: testFrom.Location()})
+
+	want := "\uFEFFBuchungsdatum;Ladestation;Authentifizierung;Beginn;Ende;Verbrauch;Status;Anomalie;Anteil\n" +
+		`01.02.2026;"Garage; left";Müller;01.02.2026 08:00:00;01.02.2026 10:00:00;12,50;completed;;` + "\n"
+	if string(out) != want {
+		t.Errorf("excel-de output =\n%s\nwant\n%s", out, want)
+	}
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/joshiste/sma_chg_log/internal/i18n"
+)
+
+// Quoting policies of CSV fields
+const (
+	// QuoteMinimal quotes fields containing the delimiter, quotes, line breaks or leading spaces
+	QuoteMinimal = "minimal"
+	// QuoteAll quotes every field
+	QuoteAll = "all"
+)
+
+// CSVDialect controls the format of the CSV output, e.g. for spreadsheets of other locales
+type CSVDialect struct {
+	Delimiter rune
+	// Decimal is the decimal separator of numbers
+	Decimal rune
+	// DateLayout and TimeLayout are the Go layouts of the record date and the start and end time
+	DateLayout string
+	TimeLayout string
+	// Language of the header row
+	Language string
+	// BOM starts the output with the UTF-8 byte order mark, so Excel detects the encoding
+	BOM   bool
+	Quote string
+}
+
+// CSVDialects are the named presets of CSV dialects
+var CSVDialects = map[string]CSVDialect{
+	"default": {
+		Delimiter:  ',',
+		Decimal:    '.',
+		DateLayout: "2006-01-02",
+		TimeLayout: time.RFC3339,
+		Language:   "en",
+		Quote:      QuoteMinimal,
+	},
+	"excel-en": {
+		Delimiter:  ',',
+		Decimal:    '.',
+		DateLayout: "2006-01-02",
+		TimeLayout: "2006-01-02 15:04:05",
+		Language:   "en",
+		BOM:        true,
+		Quote:      QuoteMinimal,
+	},
+	"excel-de": {
+		Delimiter:  ';',
+		Decimal:    ',',
+		DateLayout: "02.01.2006",
+		TimeLayout: "02.01.2006 15:04:05",
+		Language:   "de",
+		BOM:        true,
+		Quote:      QuoteMinimal,
+	},
+}
+
+// DefaultCSVDialect is the dialect used if none is given
+var DefaultCSVDialect = CSVDialects["default"]
+
+// Validate checks the dialect
+func (d CSVDialect) Validate() error {
+	var errs []error
+	if d.Delimiter == 0 || d.Delimiter == '"' || d.Delimiter == '\r' || d.Delimiter == '\n' || d.Delimiter == utf8.RuneError {
+		errs = append(errs, fmt.Errorf("invalid CSV delimiter %q", d.Delimiter))
+	}
+	if d.Decimal != '.' && d.Decimal != ',' {
+		errs = append(errs, fmt.Errorf("CSV decimal separator must be '.' or ',', not %q", d.Decimal))
+	}
+	if d.DateLayout == "" || d.TimeLayout == "" {
+		errs = append(errs, errors.New("CSV date and time layouts must not be empty"))
+	}
+	if _, err := i18n.Lookup(d.Language); err != nil {
+		errs = append(errs, fmt.Errorf("CSV header language: %w", err))
+	}
+	if d.Quote != QuoteMinimal && d.Quote != QuoteAll {
+		errs = append(errs, fmt.Errorf("CSV quoting must be '%s' or '%s'", QuoteMinimal, QuoteAll))
+	}
+	return errors.Join(errs...)
+}
+
+// header translates the column name to the header language
+func (d CSVDialect) header(name string) string {
+	locale, err := i18n.Lookup(d.Language)
+	if err != nil {
+		return name
+	}
+	return locale.T(name)
+}
+
+// number replaces the decimal point of the formatted number by the decimal separator
+func (d CSVDialect) number(formatted string) string {
+	if d.Decimal == '.' {
+		return formatted
+	}
+	return strings.Replace(formatted, ".", string(d.Decimal), 1)
+}
+
+// field quotes the field according to the quoting policy
+func (d CSVDialect) field(value string) string {
+	if d.Quote == QuoteAll || d.needsQuotes(value) {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return value
+}
+
+// needsQuotes reports whether the field cannot be written unquoted (like encoding/csv)
+func (d CSVDialect) needsQuotes(value string) bool {
+	if value == "" {
+		return false
+	}
+	if value[0] == ' ' || value[0] == '\t' {
+		return true
+	}
+	return strings.ContainsRune(value, d.Delimiter) || strings.ContainsAny(value, "\"\r\n")
+}
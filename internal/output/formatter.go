@@ -1,10 +1,14 @@
 package output
 
 import (
+	"cmp"
 	"io"
+	"slices"
 	"time"
 
+	"github.com/joshiste/sma_chg_log/internal/i18n"
 	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/pairing"
 )
 
 // MessageFormatter defines the interface for outputting individual messages
@@ -20,10 +24,120 @@ type SessionFormatter interface {
 	Flush() error
 }
 
-// Options contains options for PDF formatting
+// Options contains options for session formatting
 type Options struct {
-	From  time.Time
-	Until time.Time
+	From        time.Time
+	Until       time.Time
+	Location    *time.Location
+	Attribution pairing.Attribution
+	// UserColumns adds the user metadata from the authentication mapping to the output
+	UserColumns bool
+	// DeviceColumn adds the device the session was fetched from, for reports of several devices
+	DeviceColumn bool
+	// Currency of the session costs; costs are only output if set
+	Currency     string
+	CostDecimals int
+	// CSV is the dialect of the CSV output (default: DefaultCSVDialect)
+	CSV CSVDialect
+	// Locale of the texts, numbers and dates of PDF and XLSX reports (default: English)
+	Locale *i18n.Locale
+}
+
+// locale returns the configured locale (English if unset)
+func (o Options) locale() *i18n.Locale {
+	if o.Locale == nil {
+		return i18n.Default()
+	}
+	return o.Locale
+}
+
+// in converts t to the configured location (system timezone if unset)
+func (o Options) in(t time.Time) time.Time {
+	if o.Location == nil {
+		return t.In(time.Local)
+	}
+	return t.In(o.Location)
+}
+
+// recordDate returns the date a session is recorded at, according to the attribution policy
+func (o Options) recordDate(session models.ChargingSession) time.Time {
+	return o.in(o.Attribution.RecordTime(session, o.From, o.Until))
+}
+
+// period returns the first and last day of the report
+func (o Options) period() (time.Time, time.Time) {
+	if !o.From.IsZero() && !o.Until.Equal(models.TimeMax) {
+		return o.From, o.Until.AddDate(0, 0, -1) // until is exclusive
+	}
+	return o.From, o.Until
+}
+
+// sumConsumption sums up all consumption values
+func sumConsumption(sessions []models.ChargingSession) float64 {
+	var total float64
+	for _, session := range sessions {
+		total += session.Consumption
+	}
+	return total
+}
+
+// sumCost sums up all cost values
+func sumCost(sessions []models.ChargingSession) float64 {
+	var total float64
+	for _, session := range sessions {
+		if session.Cost != nil {
+			total += *session.Cost
+		}
+	}
+	return total
+}
+
+// sessionTotal sums up the sessions of a charger or authentication
+type sessionTotal struct {
+	Device      string
+	Name        string
+	Sessions    int
+	Consumption float64
+	Cost        float64
+}
+
+// chargerTotals sums up the sessions per device and charger, ordered by device and charger
+func chargerTotals(sessions []models.ChargingSession) []sessionTotal {
+	return sumSessions(sessions, func(session models.ChargingSession) (string, string) {
+		return session.Device, session.ChargerName
+	})
+}
+
+// authenticationTotals sums up the sessions per authentication, ordered by authentication
+func authenticationTotals(sessions []models.ChargingSession) []sessionTotal {
+	return sumSessions(sessions, func(session models.ChargingSession) (string, string) {
+		return "", session.Authentication
+	})
+}
+
+// sumSessions sums up the sessions per device and name returned by group
+func sumSessions(sessions []models.ChargingSession, group func(models.ChargingSession) (string, string)) []sessionTotal {
+	var totals []sessionTotal
+	index := make(map[[2]string]int)
+	for _, session := range sessions {
+		device, name := group(session)
+		key := [2]string{device, name}
+		i, ok := index[key]
+		if !ok {
+			i = len(totals)
+			index[key] = i
+			totals = append(totals, sessionTotal{Device: device, Name: name})
+		}
+		totals[i].Sessions++
+		totals[i].Consumption += session.Consumption
+		if session.Cost != nil {
+			totals[i].Cost += *session.Cost
+		}
+	}
+	slices.SortFunc(totals, func(a, b sessionTotal) int {
+		return cmp.Or(cmp.Compare(a.Device, b.Device), cmp.Compare(a.Name, b.Name))
+	})
+	return totals
 }
 
 // NewMessageFormatter creates a message formatter (JSON only)
@@ -36,14 +150,16 @@ func NewSessionFormatter(format string, w io.Writer) SessionFormatter {
 	return NewSessionFormatterWithOptions(format, w, Options{})
 }
 
-// NewSessionFormatterWithOptions creates a session formatter with PDF options
+// NewSessionFormatterWithOptions creates a session formatter with options
 func NewSessionFormatterWithOptions(format string, w io.Writer, opts Options) SessionFormatter {
 	switch format {
 	case "csv":
-		return NewCSVFormatter(w)
+		return NewCSVFormatterWithOptions(w, opts)
 	case "pdf":
 		return NewPDFFormatterWithOptions(w, opts)
+	case "xlsx":
+		return NewXLSXFormatterWithOptions(w, opts)
 	default:
-		return NewJSONSessionFormatter(w)
+		return NewJSONSessionFormatterWithOptions(w, opts)
 	}
 }
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/pairing"
+	"github.com/joshiste/sma_chg_log/internal/simulator"
+)
+
+// testFrom and testUntil are the range of the test reports
+var (
+	testFrom  = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	testUntil = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// simulatedSessions returns the sessions of a simulated device in the test range, newest first
+func simulatedSessions(t *testing.T) []models.ChargingSession {
+	t.Helper()
+	now := testUntil.Add(-2 * time.Hour)
+	// with this seed a car is still charging when the report is generated
+	device := simulator.NewWithOptions(simulator.Options{Seed: 11, Messages: simulator.Generate(11, now, 28)})
+
+	sessions := pairing.PairWithOptions(device.Messages(), pairing.Options{Now: now})
+	sessions = pairing.AttributeEnd.Attribute(sessions, testFrom, testUntil)
+	if len(sessions) < 10 {
+		t.Fatalf("%d simulated sessions, want more", len(sessions))
+	}
+	return sessions
+}
+
+// format writes the sessions with the formatter of the format
+func format(t *testing.T, format string, sessions []models.ChargingSession, opts Options) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	formatter := NewSessionFormatterWithOptions(format, &buf, opts)
+	if err := formatter.WriteHeader(); err != nil {
+		t.Fatalf("WriteHeader() error = %v", err)
+	}
+	for _, session := range sessions {
+		if err := formatter.WriteSession(session); err != nil {
+			t.Fatalf("WriteSession() error = %v", err)
+		}
+	}
+	if err := formatter.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// countStatus counts the sessions with the status
+func countStatus(sessions []models.ChargingSession, status models.SessionStatus) int {
+	count := 0
+	for _, s := range sessions {
+		if s.Status == status {
+			count++
+		}
+	}
+	return count
+}
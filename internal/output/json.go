@@ -3,6 +3,7 @@ package output
 import (
 	"encoding/json"
 	"io"
+	"math"
 
 	"github.com/joshiste/sma_chg_log/internal/models"
 )
@@ -21,7 +22,7 @@ func NewJSONMessageFormatter(w io.Writer) *JSONMessageFormatter {
 
 // WriteMessage writes the message as JSON (uses original raw JSON via MarshalJSON)
 func (f *JSONMessageFormatter) WriteMessage(msg models.Message) error {
-	return f.encoder.Encode(msg)
+	return f.encoder.Encode(&msg)
 }
 
 // Flush is a no-op for JSON format
@@ -29,15 +30,32 @@ func (f *JSONMessageFormatter) Flush() error {
 	return nil
 }
 
-// JSONSessionFormatter outputs charging sessions as JSON
+// JSONSessionFormatter outputs charging sessions as JSON, one object per line
 type JSONSessionFormatter struct {
 	encoder *json.Encoder
+	opts    Options
+	totals  jsonTotals
+}
+
+// jsonTotals sums up the sessions with known consumption of a report with costs
+type jsonTotals struct {
+	Sessions    int     `json:"sessions"`
+	Consumption float64 `json:"consumption"`
+	Cost        float64 `json:"cost"`
+	Currency    string  `json:"currency"`
 }
 
 // NewJSONSessionFormatter creates a new JSON session formatter
 func NewJSONSessionFormatter(w io.Writer) *JSONSessionFormatter {
+	return NewJSONSessionFormatterWithOptions(w, Options{})
+}
+
+// NewJSONSessionFormatterWithOptions creates a new JSON session formatter with options.
+// If a currency is set, a last line with the totals (e.g. {"totals":{...}}) follows the sessions.
+func NewJSONSessionFormatterWithOptions(w io.Writer, opts Options) *JSONSessionFormatter {
 	return &JSONSessionFormatter{
 		encoder: json.NewEncoder(w),
+		opts:    opts,
 	}
 }
 
@@ -48,10 +66,32 @@ func (f *JSONSessionFormatter) WriteHeader() error {
 
 // WriteSession writes a charging session as JSON
 func (f *JSONSessionFormatter) WriteSession(session models.ChargingSession) error {
+	if session.Status != models.StatusOngoing {
+		f.totals.Sessions++
+		f.totals.Consumption += session.Consumption
+		if session.Cost != nil {
+			f.totals.Cost += *session.Cost
+		}
+	}
 	return f.encoder.Encode(session)
 }
 
-// Flush is a no-op for JSON format
+// Flush writes the totals of a report with costs
 func (f *JSONSessionFormatter) Flush() error {
-	return nil
+	if f.opts.Currency == "" {
+		return nil
+	}
+	totals := f.totals
+	totals.Consumption = round(totals.Consumption, 2)
+	totals.Cost = round(totals.Cost, f.opts.CostDecimals)
+	totals.Currency = f.opts.Currency
+	return f.encoder.Encode(struct {
+		Totals jsonTotals `json:"totals"`
+	}{totals})
+}
+
+// round rounds v to the number of decimals, dropping the floating point artifacts of sums
+func round(v float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(v*scale) / scale
 }
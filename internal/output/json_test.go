@@ -0,0 +1,89 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+func TestJSONSessionFormatter(t *testing.T) {
+	sessions := simulatedSessions(t)
+
+	decoder := json.NewDecoder(bytes.NewReader(format(t, "json", sessions, Options{})))
+	var decoded []map[string]any
+	for {
+		var session map[string]any
+		if err := decoder.Decode(&session); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid JSON line %d: %v", len(decoded)+1, err)
+		}
+		decoded = append(decoded, session)
+	}
+
+	if len(decoded) != len(sessions) {
+		t.Fatalf("%d JSON lines, want %d", len(decoded), len(sessions))
+	}
+	for i, session := range decoded {
+		if session["chargerName"] != sessions[i].ChargerName || session["status"] != string(sessions[i].Status) {
+			t.Errorf("line %d = %v, want session %+v", i+1, session, sessions[i])
+		}
+		// the consumption of ongoing sessions is not known yet
+		_, hasConsumption := session["consumption"]
+		if ongoing := sessions[i].Status == models.StatusOngoing; hasConsumption == ongoing {
+			t.Errorf("line %d = %v, want consumption only for sessions that are not ongoing", i+1, session)
+		}
+	}
+	if countStatus(sessions, models.StatusOngoing) != 1 {
+		t.Errorf("want one ongoing session in the test data")
+	}
+}
+
+func TestJSONSessionFormatterTotals(t *testing.T) {
+	cost := func(v float64) *float64 { return &v }
+	sessions := []models.ChargingSession{
+		{ChargerName: "Garage", Status: models.StatusOngoing},
+		{ChargerName: "Garage", Consumption: 10.1, Cost: cost(3.03), Currency: "EUR", Status: models.StatusCompleted},
+		{ChargerName: "Garage", Consumption: 0.2, Status: models.StatusIncomplete, Anomaly: models.AnomalyMissingStart},
+		{ChargerName: "Garage", Consumption: 20.1, Cost: cost(6.03), Currency: "EUR", Status: models.StatusCompleted},
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(format(t, "json", sessions, Options{Currency: "EUR", CostDecimals: 2})), []byte("\n"))
+	if len(lines) != len(sessions)+1 {
+		t.Fatalf("%d JSON lines, want the sessions and the totals", len(lines))
+	}
+	// the consumption of the ongoing session is not known yet
+	want := `{"totals":{"sessions":3,"consumption":30.4,"cost":9.06,"currency":"EUR"}}`
+	if totals := string(lines[len(lines)-1]); totals != want {
+		t.Errorf("totals = %s, want %s", totals, want)
+	}
+
+	if out := format(t, "json", sessions, Options{}); bytes.Contains(out, []byte("totals")) {
+		t.Errorf("output without costs = %s, want no totals", out)
+	}
+}
+
+func TestJSONMessageFormatterKeepsSource(t *testing.T) {
+	var msg models.Message
+	if err := json.Unmarshal([]byte(`{"deviceName":"EV Charger Garage","messageId":9812,"custom":true}`), &msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Source = "garage"
+
+	var buf bytes.Buffer
+	if err := NewMessageFormatter(&buf).WriteMessage(msg); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+
+	var written models.Message
+	if err := json.Unmarshal(buf.Bytes(), &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Source != "garage" || written.DeviceName != "EV Charger Garage" || !bytes.Contains(buf.Bytes(), []byte(`"custom":true`)) {
+		t.Errorf("WriteMessage() = %s, want the original message with the source device", buf.Bytes())
+	}
+}
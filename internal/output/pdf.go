@@ -1,6 +1,7 @@
 package output
 
 import (
+	_ "embed"
 	"fmt"
 	"io"
 	"math"
@@ -10,27 +11,44 @@ import (
 
 	"github.com/go-pdf/fpdf"
 
+	"github.com/joshiste/sma_chg_log/internal/i18n"
 	"github.com/joshiste/sma_chg_log/internal/models"
 )
 
 const (
-	lineSpacing    = 1.15
-	dateFormat     = "02.01.2006"
-	dateTimeFormat = "02.01.2006 15:04:05"
-	headerHeight   = 16.0
-	rowHeight      = 16.0
-	bodyFontSize   = 10
+	lineSpacing  = 1.15
+	headerHeight = 16.0
+	rowHeight    = 16.0
+	bodyFontSize = 10
+	// fontFamily is the embedded UTF-8 font, so names in any script are printed
+	fontFamily = "DejaVu"
+)
+
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	fontRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	fontBold []byte
 )
 
 var (
 	colWidths  = []float64{25, 30, 45, 45, 45}
-	colHeaders = []string{"Record Date", "Consumption (kWh)", "Charger", "Authentication", "Started at\nEnded at"}
+	colHeaders = []string{"Record\nDate", "Consumption (kWh)", "Charger", "Authentication", "Started at\nEnded at"}
+
+	costColWidths = []float64{25, 27, 36, 38, 40, 24}
+
+	ongoingColWidths  = []float64{55, 55, 45, 35}
+	ongoingColHeaders = []string{"Charger", "Authentication", "Started at", "Elapsed"}
+
+	subtotalColWidths  = []float64{85, 25, 40, 40}
+	subtotalColHeaders = []string{"Charger", "Sessions", "Consumption (kWh)"}
 )
 
 // PDFFormatter outputs charging sessions
 type PDFFormatter struct {
 	writer   io.Writer
 	sessions []models.ChargingSession
+	ongoing  []models.ChargingSession
 	opts     Options
 }
 
@@ -55,19 +73,26 @@ func (f *PDFFormatter) WriteHeader() error {
 
 // WriteSession buffers sessions for later PDF generation
 func (f *PDFFormatter) WriteSession(session models.ChargingSession) error {
-	f.sessions = append(f.sessions, session)
+	if session.Status == models.StatusOngoing {
+		f.ongoing = append(f.ongoing, session)
+	} else {
+		f.sessions = append(f.sessions, session)
+	}
 	return nil
 }
 
 // Flush generates the PDF document with summary and table
 func (f *PDFFormatter) Flush() error {
 	pdf := fpdf.New("P", "mm", "A4", "") // Portrait orientation
+	pdf.AddUTF8FontFromBytes(fontFamily, "", fontRegular)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", fontBold)
+	l := f.opts.locale()
 
 	// Set up footer with page numbers
 	pdf.SetFooterFunc(func() {
 		pdf.SetY(-15)
-		pdf.SetFont("Arial", "", bodyFontSize)
-		pageStr := fmt.Sprintf("Page %d of {nb}", pdf.PageNo())
+		pdf.SetFont(fontFamily, "", bodyFontSize)
+		pageStr := l.T("Page %d of %s", pdf.PageNo(), "{nb}")
 		pdf.CellFormat(0, 10, pageStr, "", 0, "R", false, 0, "")
 	})
 	pdf.AliasNbPages("")
@@ -75,8 +100,8 @@ func (f *PDFFormatter) Flush() error {
 	pdf.AddPage()
 
 	// Write title
-	pdf.SetFont("Arial", "B", 20)
-	pdf.Cell(0, 10*lineSpacing, "CHARGING HISTORY OVERVIEW")
+	pdf.SetFont(fontFamily, "B", 20)
+	pdf.Cell(0, 10*lineSpacing, l.T("CHARGING HISTORY OVERVIEW"))
 	pdf.Ln(20) // More spacing below title
 
 	// Write summary
@@ -85,35 +110,42 @@ func (f *PDFFormatter) Flush() error {
 	// Write table
 	f.writeTable(pdf)
 
+	// Write subtotals of combined reports
+	if totals := chargerTotals(f.sessions); len(totals) > 1 {
+		f.writeSubtotalTable(pdf, totals)
+	}
+
+	// Write ongoing sessions
+	if len(f.ongoing) > 0 {
+		f.writeOngoingTable(pdf)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
 	return pdf.Output(f.writer)
 }
 
 // writeSummary writes the summary section with bold labels
 func (f *PDFFormatter) writeSummary(pdf *fpdf.Fpdf) {
 	lineHeight := 6 * lineSpacing
-	pdf.SetFont("Arial", "", bodyFontSize)
+	l := f.opts.locale()
+	pdf.SetFont(fontFamily, "", bodyFontSize)
 
 	// Created On
 	pdf.SetFontStyle("B")
-	pdf.Cell(47, lineHeight, "Created On:")
+	pdf.Cell(47, lineHeight, l.T("Created On:"))
 	pdf.SetFontStyle("")
-	pdf.Cell(0, lineHeight, time.Now().Format(dateFormat))
+	pdf.Cell(0, lineHeight, l.Date(f.opts.in(time.Now())))
 	pdf.Ln(lineHeight)
 
 	// Overview Period
 	pdf.SetFontStyle("B")
-	pdf.Cell(47, lineHeight, "Overview Period:")
+	pdf.Cell(47, lineHeight, l.T("Overview Period:"))
 	pdf.SetFontStyle("")
 
-	var fromDate, untilDate time.Time
-	if !f.opts.From.IsZero() && !f.opts.Until.Equal(models.TimeMax) {
-		fromDate = f.opts.From
-		untilDate = f.opts.Until.AddDate(0, 0, -1) // until - 1 day
-	} else {
-		fromDate = f.opts.From
-		untilDate = f.opts.Until
-	}
-	pdf.Cell(0, lineHeight, fmt.Sprintf("%s - %s", fromDate.Format(dateFormat), untilDate.Format(dateFormat)))
+	fromDate, untilDate := f.opts.period()
+	pdf.Cell(0, lineHeight, fmt.Sprintf("%s - %s", l.Date(fromDate), l.Date(untilDate)))
 	pdf.Ln(lineHeight)
 
 	// Empty line
@@ -121,53 +153,104 @@ func (f *PDFFormatter) writeSummary(pdf *fpdf.Fpdf) {
 
 	// Total Charging Records
 	pdf.SetFontStyle("B")
-	pdf.Cell(47, lineHeight, "Total Charging Records:")
+	pdf.Cell(47, lineHeight, l.T("Total Charging Records:"))
 	pdf.SetFontStyle("")
 	pdf.Cell(0, lineHeight, strconv.Itoa(len(f.sessions)))
 	pdf.Ln(lineHeight)
 
 	// Total Consumption
 	pdf.SetFontStyle("B")
-	pdf.Cell(47, lineHeight, "Total Consumption:")
+	pdf.Cell(47, lineHeight, l.T("Total Consumption:"))
 	pdf.SetFontStyle("")
-	pdf.Cell(0, lineHeight, fmt.Sprintf("%.2f kWh", f.calculateTotalConsumption()))
-	pdf.Ln(lineHeight * 2) // Extra space before table
+	pdf.Cell(0, lineHeight, l.Number(sumConsumption(f.sessions), 2)+" kWh")
+	pdf.Ln(lineHeight)
+
+	// Total Cost (only shown if a tariff is configured)
+	if f.opts.Currency != "" {
+		pdf.SetFontStyle("B")
+		pdf.Cell(47, lineHeight, l.T("Total Cost:"))
+		pdf.SetFontStyle("")
+		pdf.Cell(0, lineHeight, l.Number(sumCost(f.sessions), f.opts.CostDecimals)+" "+f.opts.Currency)
+		pdf.Ln(lineHeight)
+	}
+
+	// Ongoing Sessions (only shown if there are any)
+	if len(f.ongoing) > 0 {
+		pdf.SetFontStyle("B")
+		pdf.Cell(47, lineHeight, l.T("Ongoing Sessions:"))
+		pdf.SetFontStyle("")
+		pdf.Cell(0, lineHeight, strconv.Itoa(len(f.ongoing)))
+		pdf.Ln(lineHeight)
+	}
+
+	// Incomplete Records (only shown if there are any)
+	if anomalies := f.countAnomalies(); anomalies > 0 {
+		pdf.SetFontStyle("B")
+		pdf.Cell(47, lineHeight, l.T("Incomplete Records:"))
+		pdf.SetFontStyle("")
+		pdf.Cell(0, lineHeight, strconv.Itoa(anomalies))
+		pdf.Ln(lineHeight)
+	}
+
+	pdf.Ln(lineHeight) // Extra space before table
 }
 
-// calculateTotalConsumption sums up all consumption values
-func (f *PDFFormatter) calculateTotalConsumption() float64 {
-	var total float64
+// countAnomalies counts the sessions with a missing start or stop event
+func (f *PDFFormatter) countAnomalies() int {
+	var count int
 	for _, session := range f.sessions {
-		total += session.Consumption
+		if session.Anomaly != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// columns returns the widths and translated headers of the table columns
+func (f *PDFFormatter) columns() ([]float64, []string) {
+	headers := f.translate(colHeaders)
+	if f.opts.Currency == "" {
+		return colWidths, headers
 	}
-	return total
+	return costColWidths, append(headers, f.opts.locale().T("Cost (%s)", f.opts.Currency))
+}
+
+// translate translates the texts
+func (f *PDFFormatter) translate(texts []string) []string {
+	translated := make([]string, len(texts))
+	for i, text := range texts {
+		translated[i] = f.opts.locale().T(text)
+	}
+	return translated
 }
 
 // writeTableHeader writes the table header row
 func (f *PDFFormatter) writeTableHeader(pdf *fpdf.Fpdf) {
 	pdf.SetFontStyle("B")
 
+	widths, headers := f.columns()
+
 	//FIXME: do as for the bodies
 	x := pdf.GetX()
 	y := pdf.GetY()
-	for i, header := range colHeaders {
+	for i, header := range headers {
 		// Draw border only, no fill
-		pdf.Rect(x, y, colWidths[i], headerHeight, "D")
+		pdf.Rect(x, y, widths[i], headerHeight, "D")
 		pdf.SetXY(x, y)
 		// Center text vertically by adjusting cell height
-		lines := 1
-		if i == 1 || i == 4 { // Two-line colHeaders
+		lines := strings.Count(header, "\n") + 1
+		if pdf.GetStringWidth(header) > widths[i]-2*pdf.GetCellMargin() { // Wrapped headers
 			lines = 2
 		}
 		cellLineHeight := headerHeight / float64(lines+1)
 		if lines == 1 {
 			pdf.SetXY(x, y+(headerHeight-cellLineHeight)/2)
-			pdf.CellFormat(colWidths[i], cellLineHeight, header, "", 0, "C", false, 0, "")
+			pdf.CellFormat(widths[i], cellLineHeight, header, "", 0, "C", false, 0, "")
 		} else {
 			pdf.SetXY(x, y+(headerHeight-cellLineHeight*2)/2)
-			pdf.MultiCell(colWidths[i], cellLineHeight, header, "", "C", false)
+			pdf.MultiCell(widths[i], cellLineHeight, header, "", "C", false)
 		}
-		x += colWidths[i]
+		x += widths[i]
 	}
 	pdf.SetY(y + headerHeight)
 
@@ -176,11 +259,14 @@ func (f *PDFFormatter) writeTableHeader(pdf *fpdf.Fpdf) {
 
 // writeTable writes the data table to the PDF
 func (f *PDFFormatter) writeTable(pdf *fpdf.Fpdf) {
-	pdf.SetFont("Arial", "", bodyFontSize)
+	l := f.opts.locale()
+	pdf.SetFont(fontFamily, "", bodyFontSize)
 	pdf.SetCellMargin(2)
 
 	f.writeTableHeader(pdf)
 
+	widths, _ := f.columns()
+
 	for _, session := range f.sessions {
 		// Check if we need a new page
 		if pdf.GetY()+rowHeight > 280 { // Leave space for footer
@@ -188,16 +274,20 @@ func (f *PDFFormatter) writeTable(pdf *fpdf.Fpdf) {
 			f.writeTableHeader(pdf)
 		}
 
-		start := ""
+		start := l.T("(missing start)")
 		if !session.Start.IsZero() {
-			start = session.Start.Format(dateTimeFormat)
+			start = l.DateTime(f.opts.in(session.Start))
+		}
+		end := l.T("(missing stop)")
+		if !session.End.IsZero() {
+			end = l.DateTime(f.opts.in(session.End))
 		}
 
 		x := pdf.GetX()
 		y := pdf.GetY()
 
 		cell := func(col int, text, align string) {
-			w := colWidths[col]
+			w := widths[col]
 
 			lineCount := strings.Count(text, "\n") + 1
 			if wrappedLines := int(math.Ceil(pdf.GetStringWidth(text) / w)); wrappedLines > lineCount {
@@ -205,18 +295,143 @@ func (f *PDFFormatter) writeTable(pdf *fpdf.Fpdf) {
 			}
 
 			offset := 0.0
-			for i := 0; i < col && i < len(colWidths); i++ {
-				offset += colWidths[i]
+			for i := 0; i < col && i < len(widths); i++ {
+				offset += widths[i]
 			}
 
 			pdf.SetXY(x+offset, y)
 			pdf.MultiCell(w, 12.0/float64(lineCount), text, "1", align, false)
 		}
 
-		cell(0, session.End.Format(dateFormat), "C")
-		cell(1, strconv.FormatFloat(session.Consumption, 'f', 2, 64), "R")
-		cell(2, session.ChargerName, "L")
-		cell(3, session.Authentication, "L")
-		cell(4, fmt.Sprintf("%s\n%s", start, session.End.Format(dateTimeFormat)), "L")
+		consumption := l.Number(session.Consumption, 2)
+		if session.Share != 0 {
+			consumption += "\n" + l.T("(%s%% share)", l.Number(session.Share*100, 0))
+		}
+
+		cell(0, l.Date(f.opts.recordDate(session)), "C")
+		cell(1, consumption, "R")
+		cell(2, f.chargerText(session), "L")
+		cell(3, authenticationText(session), "L")
+		cell(4, fmt.Sprintf("%s\n%s", start, end), "L")
+		if f.opts.Currency != "" {
+			cost := ""
+			if session.Cost != nil {
+				cost = l.Number(*session.Cost, f.opts.CostDecimals)
+			}
+			cell(5, cost, "R")
+		}
+	}
+}
+
+// writeOngoingTable writes the section listing sessions that are still charging
+func (f *PDFFormatter) writeOngoingTable(pdf *fpdf.Fpdf) {
+	if pdf.GetY()+2*headerHeight+rowHeight > 280 { // Keep title, header and first row together
+		pdf.AddPage()
+	} else {
+		pdf.Ln(headerHeight)
+	}
+
+	l := f.opts.locale()
+	pdf.SetFont(fontFamily, "B", 14)
+	pdf.Cell(0, 10*lineSpacing, l.T("ONGOING SESSIONS"))
+	pdf.Ln(12)
+
+	pdf.SetFont(fontFamily, "B", bodyFontSize)
+	for i, header := range f.translate(ongoingColHeaders) {
+		pdf.CellFormat(ongoingColWidths[i], headerHeight/2, header, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFontStyle("")
+
+	for _, session := range f.ongoing {
+		if pdf.GetY()+rowHeight/2 > 280 { // Leave space for footer
+			pdf.AddPage()
+		}
+
+		pdf.CellFormat(ongoingColWidths[0], rowHeight/2, f.chargerLabel(session.Device, session.ChargerName), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(ongoingColWidths[1], rowHeight/2, session.Authentication, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(ongoingColWidths[2], rowHeight/2, l.DateTime(f.opts.in(session.Start)), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(ongoingColWidths[3], rowHeight/2, formatElapsed(l, time.Duration(session.Elapsed)), "1", 0, "R", false, 0, "")
+		pdf.Ln(-1)
+	}
+}
+
+// writeSubtotalTable writes the consumption and cost per charger
+func (f *PDFFormatter) writeSubtotalTable(pdf *fpdf.Fpdf, totals []sessionTotal) {
+	if pdf.GetY()+2*headerHeight+rowHeight > 280 { // Keep title, header and first row together
+		pdf.AddPage()
+	} else {
+		pdf.Ln(headerHeight)
+	}
+
+	l := f.opts.locale()
+	pdf.SetFont(fontFamily, "B", 14)
+	pdf.Cell(0, 10*lineSpacing, l.T("SUBTOTALS PER CHARGER"))
+	pdf.Ln(12)
+
+	headers := f.translate(subtotalColHeaders)
+	if f.opts.Currency != "" {
+		headers = append(headers, l.T("Cost (%s)", f.opts.Currency))
+	}
+
+	pdf.SetFont(fontFamily, "B", bodyFontSize)
+	for i, header := range headers {
+		pdf.CellFormat(subtotalColWidths[i], headerHeight/2, header, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFontStyle("")
+
+	for _, total := range totals {
+		if pdf.GetY()+rowHeight/2 > 280 { // Leave space for footer
+			pdf.AddPage()
+		}
+
+		pdf.CellFormat(subtotalColWidths[0], rowHeight/2, f.chargerLabel(total.Device, total.Name), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(subtotalColWidths[1], rowHeight/2, strconv.Itoa(total.Sessions), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(subtotalColWidths[2], rowHeight/2, l.Number(total.Consumption, 2), "1", 0, "R", false, 0, "")
+		if f.opts.Currency != "" {
+			pdf.CellFormat(subtotalColWidths[3], rowHeight/2, l.Number(total.Cost, f.opts.CostDecimals), "1", 0, "R", false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// chargerText returns the charger name with the device (if shown) on a second line
+func (f *PDFFormatter) chargerText(session models.ChargingSession) string {
+	if !f.opts.DeviceColumn || session.Device == "" {
+		return session.ChargerName
+	}
+	return session.ChargerName + "\n" + session.Device
+}
+
+// chargerLabel returns the charger name with the device (if shown) on one line
+func (f *PDFFormatter) chargerLabel(device, charger string) string {
+	if !f.opts.DeviceColumn || device == "" {
+		return charger
+	}
+	return fmt.Sprintf("%s (%s)", charger, device)
+}
+
+// formatElapsed formats a duration as hours and minutes (e.g. 2h 05m)
+func formatElapsed(l *i18n.Locale, d time.Duration) string {
+	d = d.Round(time.Minute)
+	return l.T("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// authenticationText returns the authentication with the user metadata (if any) on a second line
+func authenticationText(session models.ChargingSession) string {
+	if session.User == nil {
+		return session.Authentication
+	}
+
+	var details []string
+	for _, detail := range []string{session.User.EmployeeNumber, session.User.CostCenter, session.User.LicensePlate} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) == 0 {
+		return session.Authentication
 	}
+	return session.Authentication + "\n" + strings.Join(details, ", ")
 }
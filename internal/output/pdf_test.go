@@ -0,0 +1,36 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/joshiste/sma_chg_log/internal/i18n"
+)
+
+func TestPDFFormatter(t *testing.T) {
+	sessions := simulatedSessions(t)
+	german, err := i18n.Lookup("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := 1.5
+	sessions[0].Cost, sessions[0].Currency = &cost, "EUR"
+
+	for _, opts := range []Options{
+		{From: testFrom, Until: testUntil},
+		{From: testFrom, Until: testUntil, Locale: german, Currency: "EUR", CostDecimals: 2, DeviceColumn: true},
+	} {
+		out := format(t, "pdf", sessions, opts)
+
+		if !bytes.HasPrefix(out, []byte("%PDF-")) || !bytes.HasSuffix(bytes.TrimSpace(out), []byte("%%EOF")) {
+			t.Fatalf("output is not a PDF document: %.20q...", out)
+		}
+		// the sessions don't fit on one page
+		if pages := bytes.Count(out, []byte("/Type /Page\n")); pages < 2 {
+			t.Errorf("%d pages, want several", pages)
+		}
+		if fonts := bytes.Count(out, []byte("/FontFile2")); fonts != 2 {
+			t.Errorf("%d embedded fonts, want the regular and bold UTF-8 font", fonts)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// names of the sheets, translated to the language of the report
+const (
+	sessionsSheet = "Sessions"
+	summarySheet  = "Summary"
+)
+
+// XLSXFormatter outputs charging sessions as Excel workbook with a sessions and a summary sheet.
+// Dates, times and numbers are stored typed, so they are independent of the spreadsheet's locale.
+type XLSXFormatter struct {
+	writer   io.Writer
+	sessions []models.ChargingSession
+	opts     Options
+}
+
+// xlsxColumn is a column of the sessions sheet
+type xlsxColumn struct {
+	header string
+	width  float64
+	// style of the values, 0 for none
+	style int
+	value func(session models.ChargingSession) any
+}
+
+// xlsxEntry is a labelled value of the summary sheet
+type xlsxEntry struct {
+	label string
+	value any
+	// style of the value, 0 for none
+	style int
+}
+
+// xlsxStyles are the cell styles of the workbook
+type xlsxStyles struct {
+	header, date, dateTime, number, cost, percent int
+}
+
+// NewXLSXFormatter creates a new XLSX formatter
+func NewXLSXFormatter(w io.Writer) *XLSXFormatter {
+	return NewXLSXFormatterWithOptions(w, Options{})
+}
+
+// NewXLSXFormatterWithOptions creates a new XLSX formatter with options
+func NewXLSXFormatterWithOptions(w io.Writer, opts Options) *XLSXFormatter {
+	return &XLSXFormatter{
+		writer:   w,
+		sessions: make([]models.ChargingSession, 0),
+		opts:     opts,
+	}
+}
+
+// WriteHeader is a no-op for XLSX format (header is written in Flush)
+func (f *XLSXFormatter) WriteHeader() error {
+	return nil
+}
+
+// WriteSession buffers sessions for later workbook generation
+func (f *XLSXFormatter) WriteSession(session models.ChargingSession) error {
+	f.sessions = append(f.sessions, session)
+	return nil
+}
+
+// Flush generates the workbook
+func (f *XLSXFormatter) Flush() error {
+	book := excelize.NewFile()
+	defer func() {
+		_ = book.Close()
+	}()
+
+	styles, err := f.newStyles(book)
+	if err != nil {
+		return err
+	}
+
+	// the default sheet becomes the sessions sheet
+	if err := book.SetSheetName(book.GetSheetName(0), f.opts.locale().T(sessionsSheet)); err != nil {
+		return err
+	}
+	if err := f.writeSessionsSheet(book, styles); err != nil {
+		return err
+	}
+
+	if _, err := book.NewSheet(f.opts.locale().T(summarySheet)); err != nil {
+		return err
+	}
+	if err := f.writeSummarySheet(book, styles); err != nil {
+		return err
+	}
+
+	return book.Write(f.writer)
+}
+
+// newStyles registers the cell styles
+func (f *XLSXFormatter) newStyles(book *excelize.File) (xlsxStyles, error) {
+	costFormat := "0"
+	if f.opts.CostDecimals > 0 {
+		costFormat += "." + strings.Repeat("0", f.opts.CostDecimals)
+	}
+
+	var styles xlsxStyles
+	for _, s := range []struct {
+		id    *int
+		style excelize.Style
+	}{
+		{&styles.header, excelize.Style{Font: &excelize.Font{Bold: true}}},
+		{&styles.date, excelize.Style{CustomNumFmt: ptr("yyyy-mm-dd")}},
+		{&styles.dateTime, excelize.Style{CustomNumFmt: ptr("yyyy-mm-dd hh:mm:ss")}},
+		{&styles.number, excelize.Style{CustomNumFmt: ptr("0.00")}},
+		{&styles.cost, excelize.Style{CustomNumFmt: &costFormat}},
+		{&styles.percent, excelize.Style{CustomNumFmt: ptr("0.00%")}},
+	} {
+		id, err := book.NewStyle(&s.style)
+		if err != nil {
+			return xlsxStyles{}, err
+		}
+		*s.id = id
+	}
+	return styles, nil
+}
+
+// columns returns the columns of the sessions sheet
+func (f *XLSXFormatter) columns(styles xlsxStyles) []xlsxColumn {
+	l := f.opts.locale()
+	columns := []xlsxColumn{
+		{l.T("Record Date"), 12, styles.date, func(s models.ChargingSession) any {
+			return f.excelDate(f.opts.recordDate(s))
+		}},
+		{l.T("Charger"), 24, 0, func(s models.ChargingSession) any { return s.ChargerName }},
+	}
+	if f.opts.DeviceColumn {
+		columns = append(columns, xlsxColumn{l.T("Device"), 16, 0, func(s models.ChargingSession) any { return s.Device }})
+	}
+	columns = append(columns,
+		xlsxColumn{l.T("Authentication"), 24, 0, func(s models.ChargingSession) any { return s.Authentication }},
+		xlsxColumn{l.T("Start"), 20, styles.dateTime, func(s models.ChargingSession) any { return f.excelTime(s.Start) }},
+		xlsxColumn{l.T("End"), 20, styles.dateTime, func(s models.ChargingSession) any { return f.excelTime(s.End) }},
+		xlsxColumn{l.T("Consumption (kWh)"), 18, styles.number, func(s models.ChargingSession) any {
+			if s.Status == models.StatusOngoing {
+				return nil
+			}
+			return s.Consumption
+		}},
+		xlsxColumn{l.T("Status"), 12, 0, func(s models.ChargingSession) any { return l.T(string(s.Status)) }},
+		xlsxColumn{l.T("Anomaly"), 14, 0, func(s models.ChargingSession) any { return l.T(string(s.Anomaly)) }},
+		xlsxColumn{l.T("Share"), 10, styles.percent, func(s models.ChargingSession) any {
+			if s.Share == 0 {
+				return nil
+			}
+			return s.Share
+		}},
+	)
+	if f.opts.Currency != "" {
+		columns = append(columns,
+			xlsxColumn{l.T("Cost (%s)", f.opts.Currency), 12, styles.cost, func(s models.ChargingSession) any {
+				if s.Cost == nil {
+					return nil
+				}
+				return *s.Cost
+			}},
+		)
+	}
+	if f.opts.UserColumns {
+		user := func(s models.ChargingSession) models.User {
+			if s.User == nil {
+				return models.User{}
+			}
+			return *s.User
+		}
+		columns = append(columns,
+			xlsxColumn{l.T("Employee Number"), 18, 0, func(s models.ChargingSession) any { return user(s).EmployeeNumber }},
+			xlsxColumn{l.T("Cost Center"), 14, 0, func(s models.ChargingSession) any { return user(s).CostCenter }},
+			xlsxColumn{l.T("Vehicle"), 18, 0, func(s models.ChargingSession) any { return user(s).Vehicle }},
+			xlsxColumn{l.T("License Plate"), 14, 0, func(s models.ChargingSession) any { return user(s).LicensePlate }},
+		)
+	}
+	return columns
+}
+
+// writeSessionsSheet writes one row per session with frozen header row and autofilter
+func (f *XLSXFormatter) writeSessionsSheet(book *excelize.File, styles xlsxStyles) error {
+	sheet := f.opts.locale().T(sessionsSheet)
+	columns := f.columns(styles)
+
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column.header
+	}
+	if err := book.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+
+	for i, session := range f.sessions {
+		row := make([]any, len(columns))
+		for j, column := range columns {
+			row[j] = column.value(session)
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := book.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	lastRow := len(f.sessions) + 1
+	for i, column := range columns {
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return err
+		}
+		if err := book.SetColWidth(sheet, name, name, column.width); err != nil {
+			return err
+		}
+		if column.style != 0 && lastRow > 1 {
+			if err := book.SetCellStyle(sheet, name+"2", fmt.Sprintf("%s%d", name, lastRow), column.style); err != nil {
+				return err
+			}
+		}
+	}
+
+	lastHeader, err := excelize.CoordinatesToCellName(len(columns), 1)
+	if err != nil {
+		return err
+	}
+	if err := book.SetCellStyle(sheet, "A1", lastHeader, styles.header); err != nil {
+		return err
+	}
+	lastCell, err := excelize.CoordinatesToCellName(len(columns), lastRow)
+	if err != nil {
+		return err
+	}
+	if err := freezeHeader(book, sheet); err != nil {
+		return err
+	}
+	return book.AutoFilter(sheet, "A1:"+lastCell, nil)
+}
+
+// writeSummarySheet writes the overview and the totals per authentication and per charger
+func (f *XLSXFormatter) writeSummarySheet(book *excelize.File, styles xlsxStyles) error {
+	l := f.opts.locale()
+	sheet := l.T(summarySheet)
+
+	var completed []models.ChargingSession
+	var ongoing int
+	for _, session := range f.sessions {
+		if session.Status == models.StatusOngoing {
+			ongoing++
+		} else {
+			completed = append(completed, session)
+		}
+	}
+
+	from, until := f.opts.period()
+	overview := []xlsxEntry{
+		{l.T("Created On"), f.excelDate(time.Now()), styles.date},
+		{l.T("Overview Period From"), f.excelDate(from), styles.date},
+		{l.T("Overview Period Until"), f.excelDate(until), styles.date},
+		{l.T("Total Charging Records"), len(completed), 0},
+		{l.T("Total Consumption (kWh)"), sumConsumption(completed), styles.number},
+	}
+	if f.opts.Currency != "" {
+		overview = append(overview, xlsxEntry{l.T("Total Cost (%s)", f.opts.Currency), sumCost(completed), styles.cost})
+	}
+	if ongoing > 0 {
+		overview = append(overview, xlsxEntry{l.T("Ongoing Sessions"), ongoing, 0})
+	}
+
+	row := 1
+	for _, entry := range overview {
+		if err := book.SetSheetRow(sheet, fmt.Sprintf("A%d", row), &[]any{entry.label, entry.value}); err != nil {
+			return err
+		}
+		if err := setStyle(book, sheet, 1, row, styles.header); err != nil {
+			return err
+		}
+		if err := setStyle(book, sheet, 2, row, entry.style); err != nil {
+			return err
+		}
+		row++
+	}
+
+	row++
+	row, err := f.writeTotals(book, styles, sheet, row, "Authentication", authenticationTotals(completed))
+	if err != nil {
+		return err
+	}
+	row++
+	if _, err := f.writeTotals(book, styles, sheet, row, "Charger", chargerTotals(completed)); err != nil {
+		return err
+	}
+
+	widths := []float64{28, 18, 18, 18, 18}
+	for i, width := range widths {
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return err
+		}
+		if err := book.SetColWidth(sheet, name, name, width); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTotals writes a table of totals starting at row and returns the row following it
+func (f *XLSXFormatter) writeTotals(book *excelize.File, styles xlsxStyles, sheet string, row int, group string, totals []sessionTotal) (int, error) {
+	l := f.opts.locale()
+	device := f.opts.DeviceColumn && group == "Charger"
+
+	header := []any{l.T(group)}
+	if device {
+		header = append(header, l.T("Device"))
+	}
+	header = append(header, l.T("Sessions"), l.T("Consumption (kWh)"))
+	if f.opts.Currency != "" {
+		header = append(header, l.T("Cost (%s)", f.opts.Currency))
+	}
+	if err := book.SetSheetRow(sheet, fmt.Sprintf("A%d", row), &header); err != nil {
+		return 0, err
+	}
+	lastCol, err := excelize.ColumnNumberToName(len(header))
+	if err != nil {
+		return 0, err
+	}
+	if err := book.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", lastCol, row), styles.header); err != nil {
+		return 0, err
+	}
+	row++
+
+	// the consumption and cost follow the name, device and session count
+	consumptionCol := len(header) - 1
+	if f.opts.Currency != "" {
+		consumptionCol--
+	}
+	for _, total := range totals {
+		values := []any{total.Name}
+		if device {
+			values = append(values, total.Device)
+		}
+		values = append(values, total.Sessions, total.Consumption)
+		if f.opts.Currency != "" {
+			values = append(values, total.Cost)
+		}
+		if err := book.SetSheetRow(sheet, fmt.Sprintf("A%d", row), &values); err != nil {
+			return 0, err
+		}
+		if err := setStyle(book, sheet, consumptionCol+1, row, styles.number); err != nil {
+			return 0, err
+		}
+		if f.opts.Currency != "" {
+			if err := setStyle(book, sheet, consumptionCol+2, row, styles.cost); err != nil {
+				return 0, err
+			}
+		}
+		row++
+	}
+	return row, nil
+}
+
+// excelDate returns the date of t in the configured location
+func (f *XLSXFormatter) excelDate(t time.Time) any {
+	if t.IsZero() || t.Equal(models.TimeMax) {
+		return nil
+	}
+	t = f.opts.in(t)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// excelTime converts t to the configured location; workbooks have no timezone, so the wall clock time is stored
+func (f *XLSXFormatter) excelTime(t time.Time) any {
+	if t.IsZero() || t.Equal(models.TimeMax) {
+		return nil
+	}
+	t = f.opts.in(t)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// setStyle sets the style of the cell in column col (1-based) and row, 0 keeps the default style
+func setStyle(book *excelize.File, sheet string, col, row, style int) error {
+	if style == 0 {
+		return nil
+	}
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return err
+	}
+	return book.SetCellStyle(sheet, cell, cell, style)
+}
+
+// freezeHeader keeps the first row visible when scrolling
+func freezeHeader(book *excelize.File, sheet string) error {
+	return book.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+}
+
+// ptr returns a pointer to v, for the optional fields of excelize styles
+func ptr[T any](v T) *T {
+	return &v
+}
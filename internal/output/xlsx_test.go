@@ -0,0 +1,149 @@
+package output
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/joshiste/sma_chg_log/internal/i18n"
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// openWorkbook reads the workbook written by the XLSX formatter
+func openWorkbook(t *testing.T, sessions []models.ChargingSession, opts Options) *excelize.File {
+	t.Helper()
+	book, err := excelize.OpenReader(bytes.NewReader(format(t, "xlsx", sessions, opts)))
+	if err != nil {
+		t.Fatalf("invalid workbook: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = book.Close()
+	})
+	return book
+}
+
+// number returns the numeric value of a cell, failing if it is not stored as a number
+func number(t *testing.T, book *excelize.File, sheet, cell string) float64 {
+	t.Helper()
+	cellType, err := book.GetCellType(sheet, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// numbers are stored without a type attribute
+	if cellType != excelize.CellTypeUnset && cellType != excelize.CellTypeNumber {
+		t.Fatalf("%s!%s has type %d, want a number", sheet, cell, cellType)
+	}
+	raw, err := book.GetCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		t.Fatalf("%s!%s = %q, want a number", sheet, cell, raw)
+	}
+	return value
+}
+
+func TestXLSXFormatter(t *testing.T) {
+	cost := func(v float64) *float64 { return &v }
+	start := testFrom.Add(8 * time.Hour)
+	sessions := []models.ChargingSession{
+		{ChargerName: "Carport", Authentication: "card-b", Start: start.Add(48 * time.Hour), Status: models.StatusOngoing},
+		{ChargerName: "Garage", Authentication: "card-a", Start: start.Add(24 * time.Hour), End: start.Add(26 * time.Hour),
+			Consumption: 20.25, Cost: cost(6.08), Currency: "EUR", Status: models.StatusCompleted},
+		{ChargerName: "Carport", Authentication: "card-a", Start: start, End: start.Add(3 * time.Hour),
+			Consumption: 10.5, Cost: cost(3.15), Currency: "EUR", Status: models.StatusCompleted},
+	}
+
+	book := openWorkbook(t, sessions, Options{From: testFrom, Until: testUntil, Location: time.UTC, Currency: "EUR", CostDecimals: 2})
+
+	if sheets := book.GetSheetList(); !slices.Equal(sheets, []string{"Sessions", "Summary"}) {
+		t.Fatalf("sheets = %v, want Sessions and Summary", sheets)
+	}
+
+	rows, err := book.GetRows("Sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHeader := []string{"Record Date", "Charger", "Authentication", "Start", "End", "Consumption (kWh)", "Status", "Anomaly", "Share", "Cost (EUR)"}
+	if !slices.Equal(rows[0], wantHeader) {
+		t.Errorf("header = %v, want %v", rows[0], wantHeader)
+	}
+	if len(rows) != len(sessions)+1 {
+		t.Fatalf("%d rows, want %d", len(rows)-1, len(sessions))
+	}
+
+	// dates, times and numbers are typed values
+	if got, want := number(t, book, "Sessions", "D3"), 25569+float64(start.Add(24*time.Hour).Unix())/86400; math.Abs(got-want) > 1e-6 {
+		t.Errorf("start = %v, want the serial date %v", got, want)
+	}
+	if got := number(t, book, "Sessions", "F3"); got != 20.25 {
+		t.Errorf("consumption = %v, want 20.25", got)
+	}
+	if got := number(t, book, "Sessions", "J4"); got != 3.15 {
+		t.Errorf("cost = %v, want 3.15", got)
+	}
+	// the consumption of the ongoing session is not known yet
+	if value, err := book.GetCellValue("Sessions", "F2"); err != nil || value != "" {
+		t.Errorf("consumption of the ongoing session = %q, %v, want it empty", value, err)
+	}
+
+	summary, err := book.GetRows("Summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := make(map[string]string)
+	for i, row := range summary {
+		if len(row) > 1 {
+			totals[row[0]] = "B" + strconv.Itoa(i+1)
+		}
+	}
+	for label, want := range map[string]float64{
+		"Total Charging Records":  2,
+		"Total Consumption (kWh)": 30.75,
+		"Total Cost (EUR)":        9.23,
+		"Ongoing Sessions":        1,
+	} {
+		cell, ok := totals[label]
+		if !ok {
+			t.Errorf("summary has no %q, rows %v", label, summary)
+			continue
+		}
+		if got := number(t, book, "Summary", cell); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", label, got, want)
+		}
+	}
+}
+
+func TestXLSXFormatterGerman(t *testing.T) {
+	german, err := i18n.Lookup("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := testFrom.Add(8 * time.Hour)
+	sessions := []models.ChargingSession{
+		{ChargerName: "Garage", Start: start, End: start.Add(time.Hour), Consumption: 5, Status: models.StatusCompleted},
+		{ChargerName: "Garage", End: start.Add(3 * time.Hour), Consumption: 2, Status: models.StatusIncomplete, Anomaly: models.AnomalyMissingStart},
+	}
+
+	book := openWorkbook(t, sessions, Options{From: testFrom, Until: testUntil, Location: time.UTC, Locale: german})
+
+	if sheets := book.GetSheetList(); !slices.Equal(sheets, []string{"Ladevorgänge", "Zusammenfassung"}) {
+		t.Fatalf("sheets = %v, want the German sheet names", sheets)
+	}
+	rows, err := book.GetRows("Ladevorgänge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range [][2]string{{"abgeschlossen", ""}, {"unvollständig", "Beginn fehlt"}} {
+		row := append(rows[i+1], make([]string, 8)...)
+		if got := [2]string{row[6], row[7]}; got != want {
+			t.Errorf("status and anomaly of row %d = %q, want %q", i+1, got, want)
+		}
+	}
+}
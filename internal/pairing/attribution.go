@@ -0,0 +1,126 @@
+package pairing
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// Attribution is the policy deciding which range a session spanning a range boundary belongs to
+type Attribution string
+
+const (
+	// AttributeEnd attributes a session to the range it ended in
+	AttributeEnd Attribution = "end"
+	// AttributeStart attributes a session to the range it started in
+	AttributeStart Attribution = "start"
+	// AttributeSplit splits a session pro-rata by duration between the ranges it spans
+	AttributeSplit Attribution = "split"
+)
+
+// ParseAttribution parses an attribution policy, defaulting to AttributeEnd
+func ParseAttribution(s string) (Attribution, error) {
+	switch a := Attribution(s); a {
+	case "":
+		return AttributeEnd, nil
+	case AttributeEnd, AttributeStart, AttributeSplit:
+		return a, nil
+	default:
+		return "", fmt.Errorf("attribution must be '%s', '%s' or '%s'", AttributeEnd, AttributeStart, AttributeSplit)
+	}
+}
+
+// RecordTime returns the time a session is recorded at under the policy. The split part of a
+// session spanning a boundary of the range [from, until) is recorded within the range.
+func (a Attribution) RecordTime(s models.ChargingSession, from, until time.Time) time.Time {
+	start, end := span(s)
+	if a == AttributeStart {
+		return start
+	}
+	if a == AttributeSplit && start.Before(end) {
+		if !end.Before(until) {
+			return until.Add(-time.Nanosecond)
+		}
+		return maxTime(end, from)
+	}
+	return end
+}
+
+// Interval returns the part of the session attributed to the range [from, until): the session's
+// span clamped to the range under AttributeSplit, the whole span otherwise
+func (a Attribution) Interval(s models.ChargingSession, from, until time.Time) (time.Time, time.Time) {
+	start, end := span(s)
+	if a != AttributeSplit || !start.Before(end) {
+		return start, end
+	}
+	return maxTime(start, from), minTime(end, until)
+}
+
+// Attribute returns the sessions belonging to the range [from, until) under the policy, ordered
+// newest to oldest. With AttributeSplit the consumption of sessions spanning a boundary is reduced
+// to the share of their duration inside the range.
+func (a Attribution) Attribute(sessions []models.ChargingSession, from, until time.Time) []models.ChargingSession {
+	inRange := func(t time.Time) bool {
+		return !t.Before(from) && t.Before(until)
+	}
+
+	var result []models.ChargingSession
+	for _, s := range sessions {
+		start, end := span(s)
+
+		if a != AttributeSplit || !start.Before(end) {
+			if inRange(a.RecordTime(s, from, until)) {
+				result = append(result, s)
+			}
+			continue
+		}
+
+		inStart, inEnd := a.Interval(s, from, until)
+		overlap := inEnd.Sub(inStart)
+		if overlap <= 0 {
+			continue
+		}
+		if share := float64(overlap) / float64(end.Sub(start)); share < 1 {
+			s.Share = share
+			s.Consumption *= share
+		}
+		result = append(result, s)
+	}
+
+	slices.SortStableFunc(result, func(x, y models.ChargingSession) int {
+		return a.RecordTime(y, from, until).Compare(a.RecordTime(x, from, until))
+	})
+	return result
+}
+
+// span returns the start and end of a session, substituting unknown ends with the known one.
+// Ongoing sessions end at the time they were reported.
+func span(s models.ChargingSession) (time.Time, time.Time) {
+	start, end := s.Start, s.End
+	if end.IsZero() && s.Status == models.StatusOngoing {
+		end = start.Add(time.Duration(s.Elapsed))
+	}
+	if start.IsZero() {
+		start = end
+	}
+	if end.IsZero() {
+		end = start
+	}
+	return start, end
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
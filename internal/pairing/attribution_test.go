@@ -0,0 +1,81 @@
+package pairing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+func TestAttribute(t *testing.T) {
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+	may := april.AddDate(0, 1, 0)
+
+	// 2 of 4 hours in March
+	spanning := models.ChargingSession{
+		ChargerName: "EV Charger Garage",
+		Start:       april.Add(-2 * time.Hour),
+		End:         april.Add(2 * time.Hour),
+		Consumption: 40,
+		Status:      models.StatusCompleted,
+	}
+	inside := models.ChargingSession{
+		ChargerName: "EV Charger Garage",
+		Start:       march.Add(24 * time.Hour),
+		End:         march.Add(26 * time.Hour),
+		Consumption: 10,
+		Status:      models.StatusCompleted,
+	}
+	sessions := []models.ChargingSession{spanning, inside}
+
+	tests := []struct {
+		name        string
+		attribution Attribution
+		from, until time.Time
+		// consumption and record time of the attributed sessions
+		consumption []float64
+		recorded    []time.Time
+	}{
+		{"end in march", AttributeEnd, march, april, []float64{10}, []time.Time{inside.End}},
+		{"end in april", AttributeEnd, april, may, []float64{40}, []time.Time{spanning.End}},
+		{"start in march", AttributeStart, march, april, []float64{40, 10}, []time.Time{spanning.Start, inside.Start}},
+		{"start in april", AttributeStart, april, may, nil, nil},
+		// the split part is recorded within the range
+		{"split in march", AttributeSplit, march, april, []float64{20, 10}, []time.Time{april.Add(-time.Nanosecond), inside.End}},
+		{"split in april", AttributeSplit, april, may, []float64{20}, []time.Time{spanning.End}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributed := tt.attribution.Attribute(sessions, tt.from, tt.until)
+			if len(attributed) != len(tt.consumption) {
+				t.Fatalf("Attribute() = %+v, want %d sessions", attributed, len(tt.consumption))
+			}
+			for i, s := range attributed {
+				if s.Consumption != tt.consumption[i] {
+					t.Errorf("session %d consumption = %v, want %v", i, s.Consumption, tt.consumption[i])
+				}
+				recorded := tt.attribution.RecordTime(s, tt.from, tt.until)
+				if !recorded.Equal(tt.recorded[i]) {
+					t.Errorf("session %d recorded at %s, want %s", i, recorded, tt.recorded[i])
+				}
+				if recorded.Before(tt.from) || !recorded.Before(tt.until) {
+					t.Errorf("session %d recorded at %s, outside of the range", i, recorded)
+				}
+			}
+		})
+	}
+}
+
+func TestInterval(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(0, 1, 0)
+	session := models.ChargingSession{Start: from.Add(-time.Hour), End: from.Add(time.Hour), Status: models.StatusCompleted}
+
+	if start, end := AttributeSplit.Interval(session, from, until); !start.Equal(from) || !end.Equal(session.End) {
+		t.Errorf("AttributeSplit.Interval() = %s, %s, want %s, %s", start, end, from, session.End)
+	}
+	if start, end := AttributeEnd.Interval(session, from, until); !start.Equal(session.Start) || !end.Equal(session.End) {
+		t.Errorf("AttributeEnd.Interval() = %s, %s, want %s, %s", start, end, session.Start, session.End)
+	}
+}
@@ -0,0 +1,188 @@
+package pairing
+
+import (
+	"cmp"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// DefaultMaxSessionLength is the longest a session is assumed to be charging without a stop event
+const DefaultMaxSessionLength = 48 * time.Hour
+
+// Options contains options for pairing
+type Options struct {
+	// Now is the time the messages were fetched at. If set, sessions still open at the end
+	// of the messages and started within MaxSessionLength are reported as ongoing instead of
+	// missing their stop event.
+	Now time.Time
+	// MaxSessionLength is the longest a session may be ongoing (DefaultMaxSessionLength if zero)
+	MaxSessionLength time.Duration
+}
+
+// Pair pairs charging started and completed messages into sessions
+func Pair(messages []models.Message) []models.ChargingSession {
+	return PairWithOptions(messages, Options{})
+}
+
+// PairWithOptions pairs charging started and completed messages into sessions.
+// Messages may be in any order and from several chargers; open sessions are tracked per charger.
+// Starts without a stop and stops without a start are returned as sessions flagged with an anomaly.
+// The returned sessions are ordered newest to oldest.
+func PairWithOptions(messages []models.Message, opts Options) []models.ChargingSession {
+	ordered := slices.Clone(messages)
+	slices.SortStableFunc(ordered, func(a, b models.Message) int {
+		// a start and stop of the same second belong to one short session, so the start goes first
+		return cmp.Or(a.Timestamp.Compare(b.Timestamp), cmp.Compare(a.MessageID, b.MessageID))
+	})
+
+	var sessions []models.ChargingSession
+	open := make(map[string]models.Message)
+	lastStop := make(map[string]models.Message)
+
+	for _, msg := range ordered {
+		key := chargerKey(msg)
+
+		switch msg.MessageID {
+		case models.MessageIDChargingStarted:
+			if prev, ok := open[key]; ok {
+				if isDuplicate(prev, msg) {
+					slog.Debug("ignoring duplicate start event", "charger", key, "timestamp", msg.Timestamp)
+					continue
+				}
+				slog.Warn("charging start without stop", "charger", key, "timestamp", prev.Timestamp)
+				sessions = append(sessions, orphanStart(prev))
+			}
+			open[key] = msg
+
+		case models.MessageIDChargingCompleted:
+			start, ok := open[key]
+			if !ok {
+				if prev, ok := lastStop[key]; ok && isDuplicate(prev, msg) {
+					slog.Debug("ignoring duplicate stop event", "charger", key, "timestamp", msg.Timestamp)
+					continue
+				}
+				slog.Warn("charging stop without start", "charger", key, "timestamp", msg.Timestamp)
+				sessions = append(sessions, orphanStop(msg))
+			} else {
+				delete(open, key)
+				sessions = append(sessions, paired(start, msg))
+			}
+			lastStop[key] = msg
+		}
+	}
+
+	maxLength := opts.MaxSessionLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxSessionLength
+	}
+	// the open starts are the newest events of their chargers; older ones lost their stop event
+	for _, key := range slices.Sorted(maps.Keys(open)) {
+		start := open[key]
+		if !opts.Now.IsZero() && opts.Now.Sub(start.Timestamp) <= maxLength {
+			sessions = append(sessions, ongoing(start, opts.Now))
+			continue
+		}
+		slog.Warn("charging start without stop", "charger", key, "timestamp", start.Timestamp)
+		sessions = append(sessions, orphanStart(start))
+	}
+
+	slices.SortStableFunc(sessions, func(a, b models.ChargingSession) int {
+		return cmp.Compare(b.RecordTime().UnixNano(), a.RecordTime().UnixNano())
+	})
+
+	return sessions
+}
+
+// chargerKey identifies the charger a message originates from (per source device, if fetched from several)
+func chargerKey(msg models.Message) string {
+	prefix := ""
+	if msg.Source != "" {
+		prefix = msg.Source + "/"
+	}
+	if msg.DeviceSerialnumber != "" {
+		return prefix + msg.DeviceSerialnumber
+	}
+	if msg.DeviceID != "" {
+		return prefix + msg.DeviceID
+	}
+	return prefix + msg.DeviceName
+}
+
+// isDuplicate reports whether b is a repeated delivery of event a
+func isDuplicate(a, b models.Message) bool {
+	return a.Timestamp.Equal(b.Timestamp) &&
+		findAuthentication(a.Arguments) == findAuthentication(b.Arguments) &&
+		findConsumption(a.Arguments) == findConsumption(b.Arguments)
+}
+
+func paired(start, stop models.Message) models.ChargingSession {
+	return models.ChargingSession{
+		ChargerName:    stop.DeviceName,
+		Device:         stop.Source,
+		Consumption:    findConsumption(stop.Arguments),
+		Authentication: findAuthentication(start.Arguments),
+		Start:          start.Timestamp,
+		End:            stop.Timestamp,
+		Status:         models.StatusCompleted,
+	}
+}
+
+func ongoing(start models.Message, now time.Time) models.ChargingSession {
+	return models.ChargingSession{
+		ChargerName:    start.DeviceName,
+		Device:         start.Source,
+		Authentication: findAuthentication(start.Arguments),
+		Start:          start.Timestamp,
+		Status:         models.StatusOngoing,
+		Elapsed:        models.Duration(now.Sub(start.Timestamp).Truncate(time.Second)),
+	}
+}
+
+func orphanStart(start models.Message) models.ChargingSession {
+	return models.ChargingSession{
+		ChargerName:    start.DeviceName,
+		Device:         start.Source,
+		Authentication: findAuthentication(start.Arguments),
+		Start:          start.Timestamp,
+		Status:         models.StatusIncomplete,
+		Anomaly:        models.AnomalyMissingStop,
+	}
+}
+
+func orphanStop(stop models.Message) models.ChargingSession {
+	return models.ChargingSession{
+		ChargerName: stop.DeviceName,
+		Device:      stop.Source,
+		Consumption: findConsumption(stop.Arguments),
+		End:         stop.Timestamp,
+		Status:      models.StatusIncomplete,
+		Anomaly:     models.AnomalyMissingStart,
+	}
+}
+
+// findConsumption finds the consumption value from message arguments
+func findConsumption(args []models.MessageArgument) float64 {
+	for _, arg := range args {
+		if arg.UnitTag == 8 && arg.DisplayType == "Fix2" {
+			if val, err := strconv.ParseFloat(arg.Value, 64); err == nil {
+				return val
+			}
+		}
+	}
+	return 0
+}
+
+// findAuthentication finds the authentication value from message arguments
+func findAuthentication(args []models.MessageArgument) string {
+	for _, arg := range args {
+		if arg.DisplayType == "String" && arg.Position == 0 {
+			return arg.Value
+		}
+	}
+	return ""
+}
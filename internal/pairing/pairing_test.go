@@ -0,0 +1,224 @@
+package pairing
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+	"github.com/joshiste/sma_chg_log/internal/simulator"
+)
+
+var day = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+// charger is a charger sending the test messages
+type charger struct {
+	serial, name string
+}
+
+var (
+	garage  = charger{serial: "3012345678", name: "EV Charger Garage"}
+	carport = charger{serial: "3012345679", name: "EV Charger Carport"}
+)
+
+func (c charger) started(at time.Duration, card string) models.Message {
+	return models.Message{
+		DeviceName:         c.name,
+		DeviceSerialnumber: c.serial,
+		MessageID:          models.MessageIDChargingStarted,
+		Timestamp:          day.Add(at),
+		Arguments:          []models.MessageArgument{{DisplayType: "String", Position: 0, Value: card}},
+	}
+}
+
+func (c charger) completed(at time.Duration, card string, consumption float64) models.Message {
+	return models.Message{
+		DeviceName:         c.name,
+		DeviceSerialnumber: c.serial,
+		MessageID:          models.MessageIDChargingCompleted,
+		Timestamp:          day.Add(at),
+		Arguments: []models.MessageArgument{
+			{DisplayType: "String", Position: 0, Value: card},
+			{DisplayType: "Fix2", Position: 1, UnitTag: 8, Value: strconv.FormatFloat(consumption, 'f', 2, 64)},
+		},
+	}
+}
+
+// summary is the part of a session compared by the tests
+type summary struct {
+	charger        string
+	authentication string
+	start, end     time.Duration
+	consumption    float64
+	status         models.SessionStatus
+	anomaly        models.Anomaly
+}
+
+func summarize(sessions []models.ChargingSession) []summary {
+	offset := func(t time.Time) time.Duration {
+		if t.IsZero() {
+			return -1
+		}
+		return t.Sub(day)
+	}
+	result := make([]summary, len(sessions))
+	for i, s := range sessions {
+		result[i] = summary{s.ChargerName, s.Authentication, offset(s.Start), offset(s.End), s.Consumption, s.Status, s.Anomaly}
+	}
+	return result
+}
+
+func TestPairInterleavedChargers(t *testing.T) {
+	messages := []models.Message{
+		// newest first, as returned by the device
+		carport.completed(11*time.Hour, "card-b", 7.25),
+		garage.completed(10*time.Hour, "card-a", 12.5),
+		carport.started(8*time.Hour+30*time.Minute, "card-b"),
+		garage.started(8*time.Hour, "card-a"),
+	}
+
+	got := summarize(Pair(messages))
+
+	want := []summary{
+		{carport.name, "card-b", 8*time.Hour + 30*time.Minute, 11 * time.Hour, 7.25, models.StatusCompleted, ""},
+		{garage.name, "card-a", 8 * time.Hour, 10 * time.Hour, 12.5, models.StatusCompleted, ""},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Pair() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPairSameSecond(t *testing.T) {
+	// the device returns the stop of a session started in the same second first
+	messages := []models.Message{
+		garage.completed(9*time.Hour, "card-a", 0.01),
+		garage.started(9*time.Hour, "card-a"),
+		garage.completed(8*time.Hour, "card-a", 4),
+		garage.started(7*time.Hour, "card-a"),
+	}
+
+	got := summarize(Pair(messages))
+
+	want := []summary{
+		{garage.name, "card-a", 9 * time.Hour, 9 * time.Hour, 0.01, models.StatusCompleted, ""},
+		{garage.name, "card-a", 7 * time.Hour, 8 * time.Hour, 4, models.StatusCompleted, ""},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Pair() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPairAnomalies(t *testing.T) {
+	messages := []models.Message{
+		garage.completed(1*time.Hour, "card-a", 3),
+		garage.started(2*time.Hour, "card-a"),
+		// the second start implies the missing stop of the first one
+		garage.started(3*time.Hour, "card-b"),
+		garage.completed(4*time.Hour, "card-b", 5),
+		// repeated deliveries are ignored
+		garage.completed(4*time.Hour, "card-b", 5),
+		carport.started(5*time.Hour, "card-c"),
+		carport.started(5*time.Hour, "card-c"),
+		carport.completed(6*time.Hour, "card-c", 8),
+	}
+
+	got := summarize(Pair(messages))
+
+	want := []summary{
+		{carport.name, "card-c", 5 * time.Hour, 6 * time.Hour, 8, models.StatusCompleted, ""},
+		{garage.name, "card-b", 3 * time.Hour, 4 * time.Hour, 5, models.StatusCompleted, ""},
+		{garage.name, "card-a", 2 * time.Hour, -1, 0, models.StatusIncomplete, models.AnomalyMissingStop},
+		{garage.name, "", -1, 1 * time.Hour, 3, models.StatusIncomplete, models.AnomalyMissingStart},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Pair() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPairOngoing(t *testing.T) {
+	messages := []models.Message{garage.started(20*time.Hour, "card-a")}
+
+	ongoing := PairWithOptions(messages, Options{Now: day.Add(21*time.Hour + 30*time.Minute)})
+	if len(ongoing) != 1 || ongoing[0].Status != models.StatusOngoing || ongoing[0].Elapsed != models.Duration(90*time.Minute) {
+		t.Errorf("PairWithOptions() with now = %+v, want an ongoing session of 1h30m", ongoing)
+	}
+
+	incomplete := Pair(messages)
+	if len(incomplete) != 1 || incomplete[0].Anomaly != models.AnomalyMissingStop {
+		t.Errorf("Pair() = %+v, want a session missing its stop", incomplete)
+	}
+
+	// a start without stop for longer than a session may take lost its stop event
+	stale := PairWithOptions(messages, Options{Now: day.Add(20*time.Hour + DefaultMaxSessionLength + time.Minute)})
+	if len(stale) != 1 || stale[0].Status != models.StatusIncomplete || stale[0].Anomaly != models.AnomalyMissingStop {
+		t.Errorf("PairWithOptions() with a later now = %+v, want a session missing its stop", stale)
+	}
+	short := PairWithOptions(messages, Options{Now: day.Add(22 * time.Hour), MaxSessionLength: time.Hour})
+	if len(short) != 1 || short[0].Anomaly != models.AnomalyMissingStop {
+		t.Errorf("PairWithOptions() with max session length = %+v, want a session missing its stop", short)
+	}
+}
+
+func TestPairOpenSessionsAreOrdered(t *testing.T) {
+	var messages []models.Message
+	for i := range 10 {
+		c := charger{serial: "30123456" + strconv.Itoa(10+i), name: "Charger " + strconv.Itoa(i)}
+		messages = append(messages, c.started(time.Hour, "card"))
+	}
+
+	first := summarize(Pair(messages))
+	for range 20 {
+		if got := summarize(Pair(messages)); !slices.Equal(got, first) {
+			t.Fatalf("Pair() = %+v, want the same order as %+v", got, first)
+		}
+	}
+	if first[0].charger != "Charger 0" || first[9].charger != "Charger 9" {
+		t.Errorf("Pair() = %+v, want open sessions ordered by charger", first)
+	}
+}
+
+func TestPairPerSource(t *testing.T) {
+	// chargers of several devices may report the same serial number
+	start := garage.started(time.Hour, "card-a")
+	start.Source = "home"
+	stop := garage.completed(2*time.Hour, "card-b", 4)
+	stop.Source = "office"
+
+	sessions := Pair([]models.Message{start, stop})
+	if len(sessions) != 2 || sessions[0].Device != "office" || sessions[1].Device != "home" {
+		t.Errorf("Pair() = %+v, want separate sessions per device", sessions)
+	}
+}
+
+func TestPairSimulatedMessages(t *testing.T) {
+	end := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	device := simulator.NewWithOptions(simulator.Options{Seed: 42, Messages: simulator.Generate(42, end, 30)})
+
+	messages := device.Messages()
+	sessions := PairWithOptions(messages, Options{Now: end})
+
+	completed := 0
+	for _, msg := range messages {
+		if msg.MessageID == models.MessageIDChargingCompleted {
+			completed++
+		}
+	}
+	if completed == 0 {
+		t.Fatal("no charging sessions generated")
+	}
+
+	counts := make(map[models.SessionStatus]int)
+	for i, s := range sessions {
+		counts[s.Status]++
+		if s.Status == models.StatusCompleted && (!s.Start.Before(s.End) || s.Consumption <= 0 || s.Authentication == "") {
+			t.Errorf("session %d = %+v, want start before end, consumption and authentication", i, s)
+		}
+		if i > 0 && sessions[i-1].RecordTime().Before(s.RecordTime()) {
+			t.Errorf("session %d is newer than session %d", i, i-1)
+		}
+	}
+	if counts[models.StatusCompleted] != completed || counts[models.StatusIncomplete] != 0 || counts[models.StatusOngoing] > 2 {
+		t.Errorf("sessions by status = %v, want %d completed, no incomplete and at most one ongoing per charger", counts, completed)
+	}
+}
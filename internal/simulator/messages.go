@@ -0,0 +1,124 @@
+package simulator
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// Message group tags of the generated messages
+const (
+	GroupTagCharging = 1026
+	GroupTagSystem   = 832
+)
+
+// messageIDParameterChanged is the id of the generated messages not related to charging
+const messageIDParameterChanged = 10250
+
+// charger is a simulated EV charger connected to the device
+type charger struct {
+	name   string
+	serial string
+}
+
+var chargers = []charger{
+	{name: "EV Charger Garage", serial: "3012345678"},
+	{name: "EV Charger Carport", serial: "3012345679"},
+}
+
+// cards are the RFID cards used to authenticate charging sessions
+var cards = []string{"04A1B2C3D4E5F6", "04F6E5D4C3B2A1", "0489ABCDEF0123", "Guest"}
+
+// Generate creates realistic messages for the days before end: charging started (9812) and completed (9813)
+// messages of two chargers with a handful of RFID cards, interleaved with unrelated system messages.
+// A session still running at end has no completed message. The same seed yields the same messages.
+func Generate(seed uint64, end time.Time, days int) []models.Message {
+	rng := rand.New(rand.NewPCG(seed, seed^0xc4a2))
+	end = end.UTC().Truncate(time.Second)
+	start := end.AddDate(0, 0, -days).Truncate(24 * time.Hour)
+
+	var messages []models.Message
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		for _, c := range chargers {
+			if rng.Float64() < 0.3 {
+				continue
+			}
+			// sessions start in the evening and last between one and nine hours
+			started := day.Add(16*time.Hour + time.Duration(rng.IntN(6*3600))*time.Second)
+			elapsed := time.Hour + time.Duration(rng.IntN(8*3600))*time.Second
+			if !started.Before(end) {
+				continue
+			}
+			card := cards[rng.IntN(len(cards))]
+			messages = append(messages, chargingStarted(c, started, card))
+
+			completed := started.Add(elapsed)
+			if !completed.Before(end) {
+				continue
+			}
+			// 11 kW at most, usually less as the car's battery fills up
+			consumption := elapsed.Hours() * 11 * (0.4 + 0.6*rng.Float64())
+			messages = append(messages, chargingCompleted(c, completed, card, consumption))
+		}
+
+		if rng.Float64() < 0.5 {
+			t := day.Add(time.Duration(rng.IntN(24*3600)) * time.Second)
+			if t.Before(end) {
+				messages = append(messages, systemMessage(chargers[rng.IntN(len(chargers))], t))
+			}
+		}
+	}
+
+	slices.SortStableFunc(messages, func(a, b models.Message) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	for i := range messages {
+		// markers are opaque to the client, the device uses increasing ids
+		messages[i].Marker = strconv.Itoa(len(messages) - i)
+		messages[i].MessageTag = len(messages) - i
+	}
+	return messages
+}
+
+func chargingStarted(c charger, t time.Time, card string) models.Message {
+	msg := message(c, t, models.MessageIDChargingStarted, GroupTagCharging)
+	msg.Arguments = []models.MessageArgument{
+		{DisplayType: "String", Position: 0, Value: card},
+	}
+	return msg
+}
+
+func chargingCompleted(c charger, t time.Time, card string, consumption float64) models.Message {
+	msg := message(c, t, models.MessageIDChargingCompleted, GroupTagCharging)
+	msg.Arguments = []models.MessageArgument{
+		{DisplayType: "String", Position: 0, Value: card},
+		{DisplayType: "Fix2", Position: 1, UnitTag: 8, Value: strconv.FormatFloat(consumption, 'f', 2, 64)},
+	}
+	return msg
+}
+
+func systemMessage(c charger, t time.Time) models.Message {
+	msg := message(c, t, messageIDParameterChanged, GroupTagSystem)
+	msg.Arguments = []models.MessageArgument{
+		{DisplayType: "String", Position: 0, Value: "Parameter changed"},
+	}
+	return msg
+}
+
+func message(c charger, t time.Time, id, groupTag int) models.Message {
+	return models.Message{
+		DeviceID:           fmt.Sprintf("Plant:1/EVC:%s", c.serial),
+		DeviceName:         c.name,
+		DeviceSerialnumber: c.serial,
+		EscalationLevel:    0,
+		EventTypeExtension: "Info",
+		MessageGroupTag:    groupTag,
+		MessageID:          id,
+		Timestamp:          t,
+		TraceLevel:         "info",
+	}
+}
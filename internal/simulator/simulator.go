@@ -0,0 +1,365 @@
+// Package simulator implements a fake ennexOS device serving the token and message search endpoints,
+// so the client, pairing and formatters can be exercised without a charger.
+package simulator
+
+import (
+	crand "crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+const (
+	tokenPath        = "/api/v1/token"
+	refreshTokenPath = "/api/v1/refreshtoken"
+	searchPath       = "/api/v1/customermessages/search"
+)
+
+// Options configures the simulated device
+type Options struct {
+	// Username and Password are the accepted credentials ("user" and "password" if empty)
+	Username string
+	Password string
+	// Messages are the messages served (generated with Generate if nil)
+	Messages []models.Message
+	// PageSize is the number of messages per search response (100 if zero)
+	PageSize int
+	// TokenLifetime is the validity of issued tokens (no expiry if zero)
+	TokenLifetime time.Duration
+	// IgnoreFilter emulates firmware ignoring the from, until and messageGroupTags parameters
+	IgnoreFilter bool
+	// FailureRate is the fraction of search requests answered with FailureStatus
+	FailureRate float64
+	// FailureStatus is the status of injected failures (503 if zero)
+	FailureStatus int
+	// Seed seeds the generated messages and injected failures
+	Seed uint64
+}
+
+// Stats counts the requests handled by the simulator
+type Stats struct {
+	TokenRequests  int
+	Refreshes      int
+	Revocations    int
+	SearchRequests int
+	Unauthorized   int
+	Failures       int
+}
+
+// Server is a fake ennexOS device, it implements http.Handler for use with httptest
+type Server struct {
+	opts     Options
+	messages []models.Message
+	mux      *http.ServeMux
+
+	mu            sync.Mutex
+	rng           *rand.Rand
+	tokens        map[string]time.Time
+	refreshTokens map[string]struct{}
+	failures      []int
+	stats         Stats
+}
+
+// New creates a simulator with generated messages and default options
+func New() *Server {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions creates a simulator with options
+func NewWithOptions(opts Options) *Server {
+	if opts.Username == "" {
+		opts.Username = "user"
+	}
+	if opts.Password == "" {
+		opts.Password = "password"
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = 100
+	}
+	if opts.FailureStatus == 0 {
+		opts.FailureStatus = http.StatusServiceUnavailable
+	}
+
+	messages := opts.Messages
+	if messages == nil {
+		messages = Generate(opts.Seed, time.Now(), 90)
+	}
+	// the device returns the newest messages first
+	messages = slices.Clone(messages)
+	slices.SortStableFunc(messages, func(a, b models.Message) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+
+	s := &Server{
+		opts:     opts,
+		messages: messages,
+		mux:      http.NewServeMux(),
+		rng:      rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x5eed)),
+		tokens:   make(map[string]time.Time),
+
+		refreshTokens: make(map[string]struct{}),
+	}
+	s.mux.HandleFunc("POST "+tokenPath, s.handleToken)
+	s.mux.HandleFunc("DELETE "+refreshTokenPath, s.handleRevoke)
+	s.mux.HandleFunc("POST "+searchPath, s.handleSearch)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	slog.Debug("simulator request", "method", r.Method, "path", r.URL.Path)
+	s.mux.ServeHTTP(w, r)
+}
+
+// Messages returns the served messages, newest first
+func (s *Server) Messages() []models.Message {
+	return slices.Clone(s.messages)
+}
+
+// Fail answers the next search requests with the given status codes, one per request
+func (s *Server) Fail(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// ExpireTokens invalidates all issued tokens, so the next search request is answered with 401
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.tokens)
+}
+
+// Stats returns the request counters
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.stats.TokenRequests++
+	s.mu.Unlock()
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form")
+		return
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		if r.PostForm.Get("username") != s.opts.Username || r.PostForm.Get("password") != s.opts.Password {
+			writeError(w, http.StatusUnauthorized, "invalid_grant")
+			return
+		}
+		writeJSON(w, s.issueToken(randomToken()))
+	case "refresh_token":
+		refreshToken := r.PostForm.Get("refresh_token")
+		s.mu.Lock()
+		_, ok := s.refreshTokens[refreshToken]
+		s.stats.Refreshes++
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "invalid_grant")
+			return
+		}
+		writeJSON(w, s.issueToken(refreshToken))
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type")
+	}
+}
+
+// handleRevoke deletes a refresh token, like logging out of the web interface
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Revocations++
+	delete(s.refreshTokens, r.URL.Query().Get("refreshToken"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tokenResponse is the body of a successful token request
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+// issueToken creates a new access token for the refresh token
+func (s *Server) issueToken(refreshToken string) tokenResponse {
+	token := randomToken()
+	expires := models.TimeMax
+	if s.opts.TokenLifetime > 0 {
+		expires = time.Now().Add(s.opts.TokenLifetime)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = expires
+	s.refreshTokens[refreshToken] = struct{}{}
+
+	return tokenResponse{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		TokenType:    "bearer",
+		ExpiresIn:    int(s.opts.TokenLifetime.Seconds()),
+	}
+}
+
+// authorized checks the bearer token of the request
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := bearerToken(r)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires, ok := s.tokens[token]
+	if ok && !time.Now().Before(expires) {
+		delete(s.tokens, token)
+		return false
+	}
+	return ok
+}
+
+// injectFailure returns the status of an injected failure for this request (zero if none)
+func (s *Server) injectFailure() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		return status
+	}
+	if s.opts.FailureRate > 0 && s.rng.Float64() < s.opts.FailureRate {
+		return s.opts.FailureStatus
+	}
+	return 0
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.stats.SearchRequests++
+	s.mu.Unlock()
+
+	if !s.authorized(r) {
+		s.mu.Lock()
+		s.stats.Unauthorized++
+		s.mu.Unlock()
+		writeError(w, http.StatusUnauthorized, "invalid_token")
+		return
+	}
+
+	if status := s.injectFailure(); status != 0 {
+		s.mu.Lock()
+		s.stats.Failures++
+		s.mu.Unlock()
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+
+	var req models.SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	messages := s.messages
+	if !s.opts.IgnoreFilter {
+		var err error
+		if messages, err = filter(messages, req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	writeJSON(w, page(messages, req.Marker, req.Offset, s.opts.PageSize))
+}
+
+// filter returns the messages matching the time range and message groups of the request
+func filter(messages []models.Message, req models.SearchRequest) ([]models.Message, error) {
+	from, until := time.Time{}, models.TimeMax
+	if req.From != nil {
+		t, err := time.Parse(time.RFC3339, *req.From)
+		if err != nil {
+			return nil, err
+		}
+		from = t
+	}
+	if req.Until != nil {
+		t, err := time.Parse(time.RFC3339, *req.Until)
+		if err != nil {
+			return nil, err
+		}
+		until = t
+	}
+
+	var filtered []models.Message
+	for _, msg := range messages {
+		if msg.Timestamp.Before(from) || !msg.Timestamp.Before(until) {
+			continue
+		}
+		if len(req.MessageGroupTags) > 0 && !slices.Contains(req.MessageGroupTags, msg.MessageGroupTag) {
+			continue
+		}
+		filtered = append(filtered, msg)
+	}
+	return filtered, nil
+}
+
+// page returns the messages following the one with the marker, or starting at the offset if the marker is unknown
+func page(messages []models.Message, marker string, offset, size int) []models.Message {
+	start := offset
+	if marker != "" {
+		if i := slices.IndexFunc(messages, func(msg models.Message) bool { return msg.Marker == marker }); i >= 0 {
+			start = i + 1
+		}
+	}
+	start = min(max(start, 0), len(messages))
+	end := min(start+size, len(messages))
+	// an empty page is encoded as [] rather than null, as the device does
+	return append([]models.Message{}, messages[start:end]...)
+}
+
+// bearerToken returns the token of the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || auth[:len(prefix)] != prefix {
+		return "", false
+	}
+	return auth[len(prefix):], true
+}
+
+// randomToken creates an opaque token
+func randomToken() string {
+	return crand.Text()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		// the client went away, there is nobody to answer
+		slog.Debug("simulator response failed", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		slog.Debug("simulator response failed", "error", err)
+	}
+}
@@ -0,0 +1,93 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+const batchSize = 100
+
+// File reads messages from a JSON lines file as written by the events command
+type File struct {
+	path string
+}
+
+// NewFile creates a source reading the file at path ('-' for stdin)
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// FetchAllMessages implements Source
+func (f *File) FetchAllMessages(ctx context.Context, from, until time.Time, cb func(messages []models.Message) bool) error {
+	messages, err := f.readAll()
+	if err != nil {
+		return err
+	}
+
+	filtered := make([]models.Message, 0, len(messages))
+	for _, msg := range messages {
+		if !msg.Timestamp.Before(from) && msg.Timestamp.Before(until) {
+			filtered = append(filtered, msg)
+		}
+	}
+
+	for batch := range slices.Chunk(filtered, batchSize) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !cb(batch) {
+			break
+		}
+	}
+	return nil
+}
+
+// readAll reads all messages of the file ordered newest to oldest
+func (f *File) readAll() ([]models.Message, error) {
+	var r io.Reader = os.Stdin
+	if f.path != "-" {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input: %w", err)
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+		r = file
+	}
+
+	messages, err := ReadMessages(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input %s: %w", f.path, err)
+	}
+	return messages, nil
+}
+
+// ReadMessages decodes JSON lines of messages and orders them newest to oldest
+func ReadMessages(r io.Reader) ([]models.Message, error) {
+	decoder := json.NewDecoder(r)
+
+	var messages []models.Message
+	for {
+		var msg models.Message
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("message %d: %w", len(messages)+1, err)
+		}
+		messages = append(messages, msg)
+	}
+
+	slices.SortStableFunc(messages, func(a, b models.Message) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	return messages, nil
+}
@@ -0,0 +1,75 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+var start = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// fetch returns the markers of the messages of the source within the range, in the order of the batches
+func fetch(t *testing.T, src Source, from, until time.Time) []string {
+	t.Helper()
+	var markers []string
+	err := src.FetchAllMessages(context.Background(), from, until, func(messages []models.Message) bool {
+		for _, msg := range messages {
+			markers = append(markers, msg.Marker)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("FetchAllMessages() error = %v", err)
+	}
+	return markers
+}
+
+func TestFile(t *testing.T) {
+	// exported files are ordered newest first, but concatenated exports aren't
+	lines := []string{
+		`{"marker":"2","timestamp":"2026-03-01T02:00:00Z"}`,
+		`{"marker":"3","timestamp":"2026-03-01T03:00:00Z"}`,
+		`{"marker":"1","timestamp":"2026-03-01T01:00:00Z"}`,
+	}
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := NewFile(path)
+
+	if got, want := fetch(t, src, time.Time{}, models.TimeMax), []string{"3", "2", "1"}; !slices.Equal(got, want) {
+		t.Errorf("FetchAllMessages() = %v, want %v", got, want)
+	}
+	// the range is half-open
+	if got, want := fetch(t, src, start.Add(2*time.Hour), start.Add(3*time.Hour)), []string{"2"}; !slices.Equal(got, want) {
+		t.Errorf("FetchAllMessages() of a range = %v, want %v", got, want)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.jsonl")
+	if err := os.WriteFile(invalid, []byte("{\"marker\":\"1\"}\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{filepath.Join(dir, "missing.jsonl"), "failed to open input"},
+		{invalid, "message 2"},
+	}
+	for _, tt := range tests {
+		err := NewFile(tt.path).FetchAllMessages(context.Background(), time.Time{}, models.TimeMax, func([]models.Message) bool { return true })
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("FetchAllMessages(%s) error = %v, want %q", filepath.Base(tt.path), err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// Named is a source of a device with its name
+type Named struct {
+	Name   string
+	Source Source
+}
+
+// Merged fetches the messages of several devices concurrently and merges them by timestamp.
+// Each message is tagged with the name of its device in Message.Source, unless it is tagged
+// already (e.g. read from an export of several devices).
+type Merged struct {
+	sources []Named
+}
+
+// Merge creates a source merging the messages of the sources
+func Merge(sources ...Named) *Merged {
+	return &Merged{sources: sources}
+}
+
+// FetchAllMessages implements Source
+func (m *Merged) FetchAllMessages(ctx context.Context, from, until time.Time, cb func(messages []models.Message) bool) error {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]models.Message, len(m.sources))
+	errs := make([]error, len(m.sources))
+
+	var wg sync.WaitGroup
+	for i, named := range m.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := named.Source.FetchAllMessages(fetchCtx, from, until, func(messages []models.Message) bool {
+				for _, msg := range messages {
+					if msg.Source == "" {
+						msg.Source = named.Name
+					}
+					results[i] = append(results[i], msg)
+				}
+				return true
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("device %s: %w", named.Name, err)
+				// the merged messages would be incomplete, so the other devices are not waited for
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// devices cancelled because another device failed are not reported, unless the run was cancelled
+	if ctx.Err() == nil {
+		var failed []error
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, context.Canceled) {
+				failed = append(failed, err)
+			}
+		}
+		if len(failed) > 0 {
+			return errors.Join(failed...)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	merged := slices.Concat(results...)
+	slices.SortStableFunc(merged, func(a, b models.Message) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+
+	for batch := range slices.Chunk(merged, batchSize) {
+		if !cb(batch) {
+			break
+		}
+	}
+	return nil
+}
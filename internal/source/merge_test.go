@@ -0,0 +1,87 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// fakeSource serves fixed messages, or fails after the context is cancelled or immediately
+type fakeSource struct {
+	messages []models.Message
+	err      error
+	// block waits for the cancellation of the context
+	block bool
+}
+
+func (s *fakeSource) FetchAllMessages(ctx context.Context, _, _ time.Time, cb func(messages []models.Message) bool) error {
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if s.err != nil {
+		return s.err
+	}
+	cb(s.messages)
+	return nil
+}
+
+// messages returns messages of the source at the given hours, marked with the hour
+func messages(source string, hours ...int) []models.Message {
+	var result []models.Message
+	for _, hour := range hours {
+		result = append(result, models.Message{Marker: strconv.Itoa(hour), Timestamp: start.Add(time.Duration(hour) * time.Hour), Source: source})
+	}
+	return result
+}
+
+func TestMerge(t *testing.T) {
+	garage := &fakeSource{messages: messages("", 5, 3, 1)}
+	carport := &fakeSource{messages: messages("", 4, 2)}
+	// an export of several devices keeps its tags
+	export := &fakeSource{messages: messages("office", 6)}
+
+	merged := Merge(Named{"garage", garage}, Named{"carport", carport}, Named{"export", export})
+	var sources []string
+	err := merged.FetchAllMessages(context.Background(), time.Time{}, models.TimeMax, func(messages []models.Message) bool {
+		for _, msg := range messages {
+			sources = append(sources, msg.Source)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("FetchAllMessages() error = %v", err)
+	}
+	if want := []string{"office", "garage", "carport", "garage", "carport", "garage"}; !slices.Equal(sources, want) {
+		t.Errorf("merged sources = %v, want %v", sources, want)
+	}
+	if got, want := fetch(t, merged, time.Time{}, models.TimeMax), []string{"6", "5", "4", "3", "2", "1"}; !slices.Equal(got, want) {
+		t.Errorf("merged markers = %v, want %v", got, want)
+	}
+}
+
+func TestMergeFailure(t *testing.T) {
+	failure := errors.New("connection refused")
+	merged := Merge(
+		Named{"garage", &fakeSource{block: true}},
+		Named{"carport", &fakeSource{err: failure}},
+	)
+
+	err := merged.FetchAllMessages(context.Background(), time.Time{}, models.TimeMax, func([]models.Message) bool {
+		t.Error("callback called, want no messages of a failed fetch")
+		return true
+	})
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "device carport") {
+		t.Errorf("FetchAllMessages() error = %v, want the error of carport", err)
+	}
+	// the cancelled device is not reported
+	if strings.Contains(err.Error(), "garage") {
+		t.Errorf("FetchAllMessages() error = %v, want only the failed device", err)
+	}
+}
@@ -0,0 +1,15 @@
+package source
+
+import (
+	"context"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// Source provides messages, e.g. from the device or from a previously exported file
+type Source interface {
+	// FetchAllMessages calls the callback with batches of messages within the time range,
+	// ordered newest to oldest. The callback returns true to continue, false to stop.
+	FetchAllMessages(ctx context.Context, from, until time.Time, cb func(messages []models.Message) bool) error
+}
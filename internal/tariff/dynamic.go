@@ -0,0 +1,105 @@
+package tariff
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dynamicLayouts are the accepted timestamp layouts of dynamic price files
+var dynamicLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+}
+
+type slot struct {
+	start time.Time
+	price float64
+}
+
+// Dynamic is a tariff with prices for consecutive time slots (e.g. hourly spot prices).
+// Each price is valid until the start of the next slot, the last one for one hour.
+type Dynamic struct {
+	slots []slot
+}
+
+// ParseDynamicCSV parses dynamic prices from CSV rows of timestamp and price per kWh.
+// A header row is skipped. Timestamps without offset are interpreted in loc.
+func ParseDynamicCSV(r io.Reader, loc *time.Location) (*Dynamic, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var slots []slot
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		start, err := parseTimestamp(record[0], loc)
+		if err != nil {
+			if len(slots) == 0 && line == 1 {
+				continue // header row
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price %q", line, record[1])
+		}
+		slots = append(slots, slot{start: start, price: price})
+	}
+
+	if len(slots) == 0 {
+		return nil, errors.New("no prices found")
+	}
+
+	slices.SortFunc(slots, func(a, b slot) int {
+		return a.start.Compare(b.start)
+	})
+	return &Dynamic{slots: slots}, nil
+}
+
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dynamicLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// Price implements Tariff
+func (d *Dynamic) Price(t time.Time) (float64, time.Time, error) {
+	i, found := slices.BinarySearchFunc(d.slots, t, func(s slot, t time.Time) int {
+		return s.start.Compare(t)
+	})
+	if !found {
+		i-- // slot starting before t
+	}
+	if i < 0 {
+		return 0, time.Time{}, fmt.Errorf("no price available for %s", t.Format(time.RFC3339))
+	}
+
+	validUntil := d.slots[i].start.Add(time.Hour)
+	if i+1 < len(d.slots) && d.slots[i+1].start.Before(validUntil) {
+		validUntil = d.slots[i+1].start
+	}
+	if !t.Before(validUntil) {
+		return 0, time.Time{}, fmt.Errorf("no price available for %s", t.Format(time.RFC3339))
+	}
+	return d.slots[i].price, validUntil, nil
+}
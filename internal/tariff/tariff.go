@@ -0,0 +1,127 @@
+package tariff
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// Tariff provides the energy price over time
+type Tariff interface {
+	// Price returns the price per kWh at t and the time until which this price is valid
+	Price(t time.Time) (float64, time.Time, error)
+}
+
+// Load loads a tariff from its definition. The definition is either a flat price per kWh
+// (e.g. "0.30"), a YAML tariff file (.yaml/.yml) or a CSV file with dynamic prices (.csv).
+// Times without explicit offset are interpreted in loc.
+func Load(definition string, loc *time.Location) (Tariff, error) {
+	if price, err := strconv.ParseFloat(definition, 64); err == nil {
+		return NewFlat(price)
+	}
+
+	switch strings.ToLower(filepath.Ext(definition)) {
+	case ".yaml", ".yml":
+		return loadFile(definition, func(data []byte) (Tariff, error) {
+			return ParseYAML(data, loc)
+		})
+	case ".csv":
+		return loadFile(definition, func(data []byte) (Tariff, error) {
+			return ParseDynamicCSV(strings.NewReader(string(data)), loc)
+		})
+	default:
+		return nil, fmt.Errorf("tariff must be a price per kWh or a .yaml/.csv file: %s", definition)
+	}
+}
+
+func loadFile(path string, parse func([]byte) (Tariff, error)) (Tariff, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tariff: %w", err)
+	}
+	t, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tariff %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Cost calculates the cost of a charging session. The consumption is assumed to be
+// distributed evenly over the session's duration.
+func Cost(t Tariff, session models.ChargingSession) (float64, error) {
+	if session.Start.IsZero() || !session.Start.Before(session.End) {
+		at := session.RecordTime()
+		price, _, err := t.Price(at)
+		if err != nil {
+			return 0, err
+		}
+		return session.Consumption * price, nil
+	}
+
+	duration := session.End.Sub(session.Start)
+	var cost float64
+	for from := session.Start; from.Before(session.End); {
+		price, validUntil, err := t.Price(from)
+		if err != nil {
+			return 0, err
+		}
+		if !validUntil.After(from) {
+			return 0, fmt.Errorf("tariff returned no validity for price at %s", from)
+		}
+		until := validUntil
+		if until.After(session.End) {
+			until = session.End
+		}
+		cost += session.Consumption * float64(until.Sub(from)) / float64(duration) * price
+		from = until
+	}
+	return cost, nil
+}
+
+// Round rounds an amount to the nearest multiple of increment (e.g. 0.01 or 0.05)
+func Round(amount, increment float64) float64 {
+	if increment <= 0 {
+		return amount
+	}
+	rounded := math.Round(amount/increment) * increment
+	// strip floating point artifacts (e.g. 0.30000000000000004)
+	if stripped, err := strconv.ParseFloat(strconv.FormatFloat(rounded, 'f', Decimals(increment), 64), 64); err == nil {
+		return stripped
+	}
+	return rounded
+}
+
+// Decimals returns the number of decimals needed to display amounts rounded to increment
+func Decimals(increment float64) int {
+	if increment <= 0 || increment >= 1 {
+		return 0
+	}
+	s := strconv.FormatFloat(increment, 'f', -1, 64)
+	_, frac, _ := strings.Cut(s, ".")
+	return len(frac)
+}
+
+// Flat is a tariff with a constant price per kWh
+type Flat struct {
+	price float64
+}
+
+// NewFlat creates a tariff with a constant price per kWh
+func NewFlat(price float64) (*Flat, error) {
+	if price < 0 {
+		return nil, errors.New("price must not be negative")
+	}
+	return &Flat{price: price}, nil
+}
+
+// Price implements Tariff
+func (f *Flat) Price(time.Time) (float64, time.Time, error) {
+	return f.price, models.TimeMax, nil
+}
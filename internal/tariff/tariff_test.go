@@ -0,0 +1,145 @@
+package tariff
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+// writeFile writes a tariff file to a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	at := time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC) // Monday
+
+	tests := []struct {
+		name       string
+		definition string
+		price      float64
+		wantErr    string
+	}{
+		{name: "flat price", definition: "0.30", price: 0.30},
+		{name: "negative price", definition: "-0.10", wantErr: "must not be negative"},
+		{name: "flat yaml", definition: writeFile(t, "flat.yaml", "type: flat\nprice: 0.28\n"), price: 0.28},
+		{name: "time-of-use yaml", definition: writeFile(t, "tou.yml", `type: time-of-use
+default: 0.32
+windows:
+  - days: [mon, tue, wed, thu, fri]
+    from: "08:00"
+    until: "12:00"
+    price: 0.40
+`), price: 0.40},
+		{name: "dynamic csv", definition: writeFile(t, "prices.csv", "start,price\n2026-03-02 10:00,0.21\n2026-03-02 11:00,0.25\n"), price: 0.21},
+		{name: "unknown yaml type", definition: writeFile(t, "spot.yaml", "type: spot\n"), wantErr: "unknown tariff type"},
+		{name: "window without price", definition: writeFile(t, "tou.yaml", "type: time-of-use\ndefault: 0.3\nwindows:\n  - from: \"08:00\"\n    until: \"12:00\"\n"), wantErr: "window 1: price is required"},
+		{name: "invalid csv price", definition: writeFile(t, "prices.csv", "2026-03-02 10:00,cheap\n"), wantErr: "line 1: invalid price"},
+		{name: "missing file", definition: filepath.Join(t.TempDir(), "missing.yaml"), wantErr: "failed to read tariff"},
+		{name: "unknown extension", definition: "prices.json", wantErr: "must be a price per kWh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tariff, err := Load(tt.definition, time.UTC)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			price, _, err := tariff.Price(at)
+			if err != nil || price != tt.price {
+				t.Errorf("Price() = %v, %v, want %v", price, err, tt.price)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	flat, err := NewFlat(0.30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynamic, err := ParseDynamicCSV(strings.NewReader("2026-03-02 10:00,0.20\n2026-03-02 11:00,0.40\n"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tariff  Tariff
+		session models.ChargingSession
+		cost    float64
+		wantErr bool
+	}{
+		{"flat", flat, models.ChargingSession{Start: start, End: start.Add(3 * time.Hour), Consumption: 10}, 3, false},
+		// the consumption is distributed evenly: half an hour at 0.20 and half an hour at 0.40
+		{"dynamic", dynamic, models.ChargingSession{Start: start.Add(30 * time.Minute), End: start.Add(90 * time.Minute), Consumption: 8}, 2.4, false},
+		// priced at the record time
+		{"missing start", dynamic, models.ChargingSession{End: start.Add(time.Hour), Consumption: 5}, 2, false},
+		{"gap in prices", dynamic, models.ChargingSession{Start: start.Add(90 * time.Minute), End: start.Add(3 * time.Hour), Consumption: 5}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, err := Cost(tt.tariff, tt.session)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Cost() error = %v, want error %v", err, tt.wantErr)
+			}
+			if math.Abs(cost-tt.cost) > 1e-9 {
+				t.Errorf("Cost() = %v, want %v", cost, tt.cost)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount, increment, want float64
+	}{
+		{1.234, 0.01, 1.23},
+		{1.235001, 0.01, 1.24},
+		{0.1 + 0.2, 0.01, 0.3},
+		{1.27, 0.05, 1.25},
+		{1.28, 0.05, 1.3},
+		{12.6, 1, 13},
+		{1.234, 0, 1.234},
+	}
+	for _, tt := range tests {
+		if got := Round(tt.amount, tt.increment); got != tt.want {
+			t.Errorf("Round(%v, %v) = %v, want %v", tt.amount, tt.increment, got, tt.want)
+		}
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	tests := []struct {
+		increment float64
+		want      int
+	}{
+		{0.01, 2},
+		{0.05, 2},
+		{0.1, 1},
+		{0.001, 3},
+		{1, 0},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := Decimals(tt.increment); got != tt.want {
+			t.Errorf("Decimals(%v) = %d, want %d", tt.increment, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package tariff
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Window is a recurring time window with its own price. A window whose until
+// is not after from spans midnight; days refers to the day the window starts on.
+type Window struct {
+	Days  []time.Weekday
+	From  time.Duration
+	Until time.Duration
+	Price float64
+}
+
+// TimeOfUse is a tariff with prices depending on weekday and time of day.
+// The first matching window wins, outside of all windows the default price applies.
+type TimeOfUse struct {
+	defaultPrice float64
+	windows      []Window
+	location     *time.Location
+	edges        []time.Duration
+}
+
+// NewTimeOfUse creates a time-of-use tariff evaluated in loc
+func NewTimeOfUse(defaultPrice float64, windows []Window, loc *time.Location) *TimeOfUse {
+	edges := []time.Duration{0}
+	for _, w := range windows {
+		edges = append(edges, w.From, w.Until)
+	}
+	slices.Sort(edges)
+
+	return &TimeOfUse{
+		defaultPrice: defaultPrice,
+		windows:      windows,
+		location:     loc,
+		edges:        slices.Compact(edges),
+	}
+}
+
+// Price implements Tariff
+func (t *TimeOfUse) Price(at time.Time) (float64, time.Time, error) {
+	at = at.In(t.location)
+	clock := time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute +
+		time.Duration(at.Second())*time.Second + time.Duration(at.Nanosecond())
+
+	price := t.defaultPrice
+	for _, w := range t.windows {
+		if w.matches(at.Weekday(), clock) {
+			price = w.Price
+			break
+		}
+	}
+
+	// the price may only change at one of the window edges or at midnight
+	nextEdge := 24 * time.Hour
+	for _, edge := range t.edges {
+		if edge > clock {
+			nextEdge = edge
+			break
+		}
+	}
+	// the edges are wall clock times, which differ from the elapsed time since midnight on DST transition days
+	next := time.Date(at.Year(), at.Month(), at.Day(), int(nextEdge/time.Hour), int(nextEdge%time.Hour/time.Minute), 0, 0, t.location)
+
+	return price, next, nil
+}
+
+func (w Window) matches(day time.Weekday, clock time.Duration) bool {
+	if w.From < w.Until {
+		return slices.Contains(w.Days, day) && clock >= w.From && clock < w.Until
+	}
+	// window spans midnight: either in the evening part or in the morning part of the previous day's window
+	previous := (day + 6) % 7
+	return (slices.Contains(w.Days, day) && clock >= w.From) ||
+		(slices.Contains(w.Days, previous) && clock < w.Until)
+}
+
+// yamlTariff is the YAML representation of a tariff
+type yamlTariff struct {
+	Type    string       `yaml:"type"`
+	Price   *float64     `yaml:"price"`
+	Default *float64     `yaml:"default"`
+	Windows []yamlWindow `yaml:"windows"`
+}
+
+type yamlWindow struct {
+	Days  []string `yaml:"days"`
+	From  string   `yaml:"from"`
+	Until string   `yaml:"until"`
+	Price *float64 `yaml:"price"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseYAML parses a YAML tariff definition of type flat or time-of-use
+func ParseYAML(data []byte, loc *time.Location) (Tariff, error) {
+	var def yamlTariff
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, err
+	}
+
+	switch def.Type {
+	case "flat":
+		if def.Price == nil {
+			return nil, errors.New("flat tariff requires a price")
+		}
+		return NewFlat(*def.Price)
+
+	case "time-of-use":
+		if def.Default == nil {
+			return nil, errors.New("time-of-use tariff requires a default price")
+		}
+		windows := make([]Window, 0, len(def.Windows))
+		for i, w := range def.Windows {
+			window, err := w.parse()
+			if err != nil {
+				return nil, fmt.Errorf("window %d: %w", i+1, err)
+			}
+			windows = append(windows, window)
+		}
+		return NewTimeOfUse(*def.Default, windows, loc), nil
+
+	default:
+		return nil, fmt.Errorf("unknown tariff type %q (supported: flat, time-of-use)", def.Type)
+	}
+}
+
+func (w yamlWindow) parse() (Window, error) {
+	var window Window
+
+	if w.Price == nil {
+		return window, errors.New("price is required")
+	}
+	window.Price = *w.Price
+
+	if len(w.Days) == 0 {
+		w.Days = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+	}
+	for _, d := range w.Days {
+		day, ok := weekdays[strings.ToLower(d)[:min(3, len(d))]]
+		if !ok {
+			return window, fmt.Errorf("invalid day %q", d)
+		}
+		window.Days = append(window.Days, day)
+	}
+
+	var err error
+	if window.From, err = parseClock(w.From); err != nil {
+		return window, fmt.Errorf("invalid from: %w", err)
+	}
+	if window.Until, err = parseClock(w.Until); err != nil {
+		return window, fmt.Errorf("invalid until: %w", err)
+	}
+	return window, nil
+}
+
+// parseClock parses a time of day in format HH:MM (24:00 is allowed as end of day)
+func parseClock(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q must be in format HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
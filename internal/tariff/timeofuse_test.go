@@ -0,0 +1,78 @@
+package tariff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+func TestTimeOfUsePrice(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	tariff := NewTimeOfUse(0.32, []Window{
+		{Days: weekdays, From: 22 * time.Hour, Until: 6 * time.Hour, Price: 0.24},
+		{Days: []time.Weekday{time.Saturday, time.Sunday}, From: 0, Until: 24 * time.Hour, Price: 0.20},
+	}, berlin)
+
+	local := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, berlin)
+	}
+	tests := []struct {
+		name       string
+		at         time.Time
+		price      float64
+		validUntil time.Time
+	}{
+		{"weekday", local(2026, 3, 3, 12, 0), 0.32, local(2026, 3, 3, 22, 0)},
+		{"evening", local(2026, 3, 3, 23, 0), 0.24, local(2026, 3, 4, 0, 0)},
+		{"after midnight", local(2026, 3, 4, 1, 0), 0.24, local(2026, 3, 4, 6, 0)},
+		// Friday night's window continues into Saturday
+		{"saturday morning", local(2026, 3, 7, 5, 0), 0.24, local(2026, 3, 7, 6, 0)},
+		{"saturday", local(2026, 3, 7, 6, 0), 0.20, local(2026, 3, 7, 22, 0)},
+		// the clocks change on Sunday, the windows follow the wall clock
+		{"dst start", local(2026, 3, 29, 1, 0), 0.20, local(2026, 3, 29, 6, 0)},
+		{"dst end", local(2026, 10, 25, 1, 0), 0.20, local(2026, 10, 25, 6, 0)},
+		{"monday after dst start", local(2026, 3, 30, 3, 0), 0.32, local(2026, 3, 30, 6, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, validUntil, err := tariff.Price(tt.at)
+			if err != nil {
+				t.Fatalf("Price() error = %v", err)
+			}
+			if price != tt.price || !validUntil.Equal(tt.validUntil) {
+				t.Errorf("Price(%s) = %v until %s, want %v until %s", tt.at, price, validUntil, tt.price, tt.validUntil)
+			}
+		})
+	}
+}
+
+func TestTimeOfUseCostOnDSTChange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tariff := NewTimeOfUse(0.30, []Window{
+		{Days: []time.Weekday{time.Sunday}, From: 6 * time.Hour, Until: 8 * time.Hour, Price: 0.50},
+	}, berlin)
+
+	// 7 hours from 01:00 to 09:00 on the day the clocks go forward, 2 of them within the window
+	session := models.ChargingSession{
+		Start:       time.Date(2026, 3, 29, 1, 0, 0, 0, berlin),
+		End:         time.Date(2026, 3, 29, 9, 0, 0, 0, berlin),
+		Consumption: 7,
+	}
+
+	cost, err := Cost(tariff, session)
+	if err != nil {
+		t.Fatalf("Cost() error = %v", err)
+	}
+	// 1 kWh per hour, 5 hours at 0.30 and 2 hours at 0.50
+	if want := 5*0.30 + 2*0.50; Round(cost, 0.0001) != Round(want, 0.0001) {
+		t.Errorf("Cost() = %v, want %v", cost, want)
+	}
+}
@@ -0,0 +1,183 @@
+package timerange
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+const (
+	dateLayout  = "2006-01-02"
+	monthLayout = "2006-01"
+	yearLayout  = "2006"
+)
+
+// Keywords lists the supported relative period keywords
+var Keywords = []string{
+	"today",
+	"yesterday",
+	"this-month",
+	"last-month",
+	"this-quarter",
+	"last-quarter",
+	"this-year",
+	"last-year",
+}
+
+// Range is a half-open time window [From, Until)
+type Range struct {
+	From  time.Time
+	Until time.Time
+}
+
+// Unbounded returns a range covering the full history
+func Unbounded() Range {
+	return Range{Until: models.TimeMax}
+}
+
+// Spec holds the raw range selectors as given on the command line
+type Spec struct {
+	From    string
+	Until   string
+	Month   string
+	Year    string
+	Quarter string
+	Period  string
+}
+
+// Resolve turns the spec into a range, rejecting conflicting combinations.
+// Calendar boundaries are computed in loc, relative keywords are evaluated against now.
+func (s Spec) Resolve(now time.Time, loc *time.Location) (Range, error) {
+	var selectors []string
+	for _, sel := range []struct{ name, value string }{
+		{"--month", s.Month},
+		{"--year", s.Year},
+		{"--quarter", s.Quarter},
+		{"--period", s.Period},
+	} {
+		if sel.value != "" {
+			selectors = append(selectors, sel.name)
+		}
+	}
+
+	if len(selectors) > 1 {
+		return Range{}, fmt.Errorf("%s are mutually exclusive", strings.Join(selectors, ", "))
+	}
+	if len(selectors) == 1 && (s.From != "" || s.Until != "") {
+		return Range{}, fmt.Errorf("%s cannot be combined with --from/--until", selectors[0])
+	}
+
+	switch {
+	case s.Month != "":
+		return Month(s.Month, loc)
+	case s.Year != "":
+		return Year(s.Year, loc)
+	case s.Quarter != "":
+		return Quarter(s.Quarter, loc)
+	case s.Period != "":
+		return Relative(s.Period, now.In(loc))
+	default:
+		return Dates(s.From, s.Until, loc)
+	}
+}
+
+// Month parses a month in format YYYY-MM
+func Month(s string, loc *time.Location) (Range, error) {
+	parsed, err := time.ParseInLocation(monthLayout, s, loc)
+	if err != nil {
+		return Range{}, errors.New("month must be in format YYYY-MM")
+	}
+	return Range{From: parsed, Until: parsed.AddDate(0, 1, 0)}, nil
+}
+
+// Year parses a year in format YYYY
+func Year(s string, loc *time.Location) (Range, error) {
+	parsed, err := time.ParseInLocation(yearLayout, s, loc)
+	if err != nil {
+		return Range{}, errors.New("year must be in format YYYY")
+	}
+	return Range{From: parsed, Until: parsed.AddDate(1, 0, 0)}, nil
+}
+
+// Quarter parses a quarter in format YYYY-QN
+func Quarter(s string, loc *time.Location) (Range, error) {
+	errFormat := errors.New("quarter must be in format YYYY-QN (e.g. 2026-Q2)")
+
+	yearPart, quarterPart, ok := strings.Cut(strings.ToUpper(s), "-Q")
+	if !ok {
+		return Range{}, errFormat
+	}
+	year, err := time.ParseInLocation(yearLayout, yearPart, loc)
+	if err != nil {
+		return Range{}, errFormat
+	}
+	quarter, err := strconv.Atoi(quarterPart)
+	if err != nil || quarter < 1 || quarter > 4 {
+		return Range{}, errFormat
+	}
+
+	from := year.AddDate(0, (quarter-1)*3, 0)
+	return Range{From: from, Until: from.AddDate(0, 3, 0)}, nil
+}
+
+// Dates parses an explicit date range in format YYYY-MM-DD. Both ends are optional,
+// the until date is inclusive.
+func Dates(from, until string, loc *time.Location) (Range, error) {
+	r := Unbounded()
+
+	if from != "" {
+		parsed, err := time.ParseInLocation(dateLayout, from, loc)
+		if err != nil {
+			return Range{}, errors.New("from must be in format YYYY-MM-DD")
+		}
+		r.From = parsed
+	}
+
+	if until != "" {
+		parsed, err := time.ParseInLocation(dateLayout, until, loc)
+		if err != nil {
+			return Range{}, errors.New("until must be in format YYYY-MM-DD")
+		}
+		r.Until = parsed.AddDate(0, 0, 1)
+	}
+
+	if !r.From.Before(r.Until) {
+		return Range{}, errors.New("from must not be after until")
+	}
+
+	return r, nil
+}
+
+// Relative resolves a period keyword (e.g. last-month) relative to now.
+// The boundaries are computed in the location of now.
+func Relative(keyword string, now time.Time) (Range, error) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	month := day.AddDate(0, 0, 1-day.Day())
+	quarter := month.AddDate(0, -int(month.Month()-1)%3, 0)
+	year := month.AddDate(0, -int(month.Month()-1), 0)
+
+	switch keyword {
+	case "today":
+		return Range{From: day, Until: day.AddDate(0, 0, 1)}, nil
+	case "yesterday":
+		return Range{From: day.AddDate(0, 0, -1), Until: day}, nil
+	case "this-month":
+		return Range{From: month, Until: month.AddDate(0, 1, 0)}, nil
+	case "last-month":
+		return Range{From: month.AddDate(0, -1, 0), Until: month}, nil
+	case "this-quarter":
+		return Range{From: quarter, Until: quarter.AddDate(0, 3, 0)}, nil
+	case "last-quarter":
+		return Range{From: quarter.AddDate(0, -3, 0), Until: quarter}, nil
+	case "this-year":
+		return Range{From: year, Until: year.AddDate(1, 0, 0)}, nil
+	case "last-year":
+		return Range{From: year.AddDate(-1, 0, 0), Until: year}, nil
+	default:
+		return Range{}, fmt.Errorf("unknown period %q (supported: %s)", keyword, strings.Join(Keywords, ", "))
+	}
+}
@@ -0,0 +1,81 @@
+package timerange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshiste/sma_chg_log/internal/models"
+)
+
+func TestResolve(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	// a Wednesday in the second quarter
+	now := time.Date(2026, 5, 13, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		spec    Spec
+		loc     *time.Location
+		want    Range
+		wantErr string
+	}{
+		{name: "unbounded", want: Range{Until: models.TimeMax}},
+		{name: "from and until", spec: Spec{From: "2026-01-10", Until: "2026-01-20"}, want: Range{From: date(2026, 1, 10), Until: date(2026, 1, 21)}},
+		{name: "single day", spec: Spec{From: "2026-01-10", Until: "2026-01-10"}, want: Range{From: date(2026, 1, 10), Until: date(2026, 1, 11)}},
+		{name: "only from", spec: Spec{From: "2026-01-10"}, want: Range{From: date(2026, 1, 10), Until: models.TimeMax}},
+		{name: "only until", spec: Spec{Until: "2026-01-20"}, want: Range{Until: date(2026, 1, 21)}},
+		{name: "from after until", spec: Spec{From: "2026-01-20", Until: "2026-01-10"}, wantErr: "from must not be after until"},
+		{name: "invalid from", spec: Spec{From: "10.01.2026"}, wantErr: "from must be in format YYYY-MM-DD"},
+		{name: "month", spec: Spec{Month: "2026-12"}, want: Range{From: date(2026, 12, 1), Until: date(2027, 1, 1)}},
+		{name: "invalid month", spec: Spec{Month: "2026-13"}, wantErr: "month must be in format YYYY-MM"},
+		{name: "year", spec: Spec{Year: "2025"}, want: Range{From: date(2025, 1, 1), Until: date(2026, 1, 1)}},
+		{name: "quarter", spec: Spec{Quarter: "2026-Q4"}, want: Range{From: date(2026, 10, 1), Until: date(2027, 1, 1)}},
+		{name: "lower case quarter", spec: Spec{Quarter: "2026-q2"}, want: Range{From: date(2026, 4, 1), Until: date(2026, 7, 1)}},
+		{name: "invalid quarter", spec: Spec{Quarter: "2026-Q5"}, wantErr: "quarter must be in format YYYY-QN"},
+		{name: "today", spec: Spec{Period: "today"}, want: Range{From: date(2026, 5, 13), Until: date(2026, 5, 14)}},
+		{name: "yesterday", spec: Spec{Period: "yesterday"}, want: Range{From: date(2026, 5, 12), Until: date(2026, 5, 13)}},
+		{name: "this month", spec: Spec{Period: "this-month"}, want: Range{From: date(2026, 5, 1), Until: date(2026, 6, 1)}},
+		{name: "last month", spec: Spec{Period: "last-month"}, want: Range{From: date(2026, 4, 1), Until: date(2026, 5, 1)}},
+		{name: "this quarter", spec: Spec{Period: "this-quarter"}, want: Range{From: date(2026, 4, 1), Until: date(2026, 7, 1)}},
+		{name: "last quarter", spec: Spec{Period: "last-quarter"}, want: Range{From: date(2026, 1, 1), Until: date(2026, 4, 1)}},
+		{name: "this year", spec: Spec{Period: "this-year"}, want: Range{From: date(2026, 1, 1), Until: date(2027, 1, 1)}},
+		{name: "last year", spec: Spec{Period: "last-year"}, want: Range{From: date(2025, 1, 1), Until: date(2026, 1, 1)}},
+		{name: "unknown period", spec: Spec{Period: "last-week"}, wantErr: `unknown period "last-week"`},
+		{name: "exclusive selectors", spec: Spec{Month: "2026-01", Year: "2026"}, wantErr: "--month, --year are mutually exclusive"},
+		{name: "selector with dates", spec: Spec{Period: "today", From: "2026-01-01"}, wantErr: "--period cannot be combined with --from/--until"},
+		// the month starts at midnight in the location, not in UTC
+		{name: "month in location", spec: Spec{Month: "2026-03"}, loc: berlin,
+			want: Range{From: time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC), Until: time.Date(2026, 3, 31, 22, 0, 0, 0, time.UTC)}},
+		// the day of now is the day in the location
+		{name: "today in location", spec: Spec{Period: "today"}, loc: time.FixedZone("UTC+10", 10*60*60),
+			want: Range{From: time.Date(2026, 5, 13, 14, 0, 0, 0, time.UTC), Until: time.Date(2026, 5, 14, 14, 0, 0, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			got, err := tt.spec.Resolve(now, loc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if !got.From.Equal(tt.want.From) || !got.Until.Equal(tt.want.Until) {
+				t.Errorf("Resolve() = [%s, %s), want [%s, %s)", got.From, got.Until, tt.want.From, tt.want.Until)
+			}
+		})
+	}
+}
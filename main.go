@@ -1,6 +1,10 @@
 package main
 
-import "github.com/joshiste/sma_chg_log/cmd"
+import (
+	_ "time/tzdata"
+
+	"github.com/joshiste/sma_chg_log/cmd"
+)
 
 func main() {
 	cmd.Execute()